@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// only allow requests with "Authorization: Bearer <ADMIN_TOKEN>"
+// the endpoints are disabled as long as ADMIN_TOKEN is not set
+func requireAdminToken(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := os.Getenv("ADMIN_TOKEN")
+		if len(token) < 1 {
+			writeJSONError(w, http.StatusNotFound, "admin endpoints are disabled, ADMIN_TOKEN not set")
+			return
+		}
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			writeJSONError(w, http.StatusUnauthorized, "invalid admin token")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// ACL rules of all users, read-only to avoid locking out the app
+func aclList(w http.ResponseWriter, r *http.Request) {
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	rules, err := client.Do(ctx, client.B().AclList().Build()).AsStrSlice()
+	if err != nil {
+		log.Printf("Failed to fetch ACL rules, err = %v\n", valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to fetch ACL rules")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rules)
+}
+
+// user of the app connection
+func aclWhoami(w http.ResponseWriter, r *http.Request) {
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	username, err := client.Do(ctx, client.B().AclWhoami().Build()).ToString()
+	if err != nil {
+		log.Printf("Failed to fetch ACL user, err = %v\n", valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to fetch ACL user")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"username": username})
+}
+
+// command categories, or the commands of ?category=
+func aclCat(w http.ResponseWriter, r *http.Request) {
+	category := r.URL.Query().Get("category")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	cmd := client.B().AclCat().Build()
+	if len(category) > 0 {
+		cmd = client.B().AclCat().Categoryname(category).Build()
+	}
+	names, err := client.Do(ctx, cmd).AsStrSlice()
+	if err != nil {
+		log.Printf("Failed to fetch ACL categories, err = %v\n", valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to fetch ACL categories")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, names)
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// a latency spike recorded by the latency monitor
+type LatencySample struct {
+	Timestamp int64 `json:"timestamp"`
+	LatencyMs int64 `json:"latency_ms"`
+}
+
+// latest and maximum latency of an event type
+type LatencyEvent struct {
+	Event     string `json:"event"`
+	Timestamp int64  `json:"timestamp"`
+	LatestMs  int64  `json:"latest_ms"`
+	MaxMs     int64  `json:"max_ms"`
+}
+
+// the latency monitor only records events above latency-monitor-threshold
+func latencyHistory(w http.ResponseWriter, r *http.Request) {
+	event := r.URL.Query().Get("event")
+	if len(event) < 1 {
+		writeJSONError(w, http.StatusBadRequest, "query parameter event is required")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	entries, err := client.Do(ctx, client.B().LatencyHistory().Event(event).Build()).ToArray()
+	if err != nil {
+		log.Printf("Failed to fetch latency history of %v, err = %v\n", event, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch latency history of %v", event))
+		return
+	}
+
+	samples := make([]LatencySample, 0, len(entries))
+	for _, entry := range entries {
+		values, err := entry.AsIntSlice()
+		if err != nil || len(values) < 2 {
+			log.Printf("Skipping invalid latency sample of %v: %v", event, err)
+			continue
+		}
+		samples = append(samples, LatencySample{Timestamp: values[0], LatencyMs: values[1]})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"event":   event,
+		"samples": samples,
+	})
+}
+
+func latencyLatest(w http.ResponseWriter, r *http.Request) {
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	entries, err := client.Do(ctx, client.B().LatencyLatest().Build()).ToArray()
+	if err != nil {
+		log.Printf("Failed to fetch latest latencies, err = %v\n", valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to fetch latest latencies")
+		return
+	}
+
+	events := make([]LatencyEvent, 0, len(entries))
+	for _, entry := range entries {
+		// event name, timestamp, latest and max latency
+		fields, err := entry.ToArray()
+		if err != nil || len(fields) < 4 {
+			log.Printf("Skipping invalid latency event: %v", err)
+			continue
+		}
+		var event LatencyEvent
+		if event.Event, err = fields[0].ToString(); err != nil {
+			continue
+		}
+		event.Timestamp, _ = fields[1].AsInt64()
+		event.LatestMs, _ = fields[2].AsInt64()
+		event.MaxMs, _ = fields[3].AsInt64()
+		events = append(events, event)
+	}
+
+	writeJSON(w, http.StatusOK, events)
+}
+
+// reset the samples of all events or of ?event=
+func latencyReset(w http.ResponseWriter, r *http.Request) {
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	cmd := client.B().LatencyReset().Build()
+	if event := r.URL.Query().Get("event"); len(event) > 0 {
+		cmd = client.B().LatencyReset().Event(event).Build()
+	}
+	// number of reset event time series
+	reset, err := replyInt64(client.Do(ctx, cmd))
+	if err != nil {
+		log.Printf("Failed to reset latency samples, err = %v\n", valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to reset latency samples")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"reset": reset})
+}
+
+// commands sent by the app, see /admin/commands
+var appCommands = []string{"SET", "GET", "DEL", "UNLINK", "SCAN", "TYPE", "TTL", "MGET"}
+
+// COMMAND INFO of a command
+type CommandInfo struct {
+	Name     string   `json:"name"`
+	Arity    int64    `json:"arity"`
+	Flags    []string `json:"flags"`
+	FirstKey int64    `json:"first_key"`
+	LastKey  int64    `json:"last_key"`
+	Step     int64    `json:"step"`
+}
+
+// server side metadata of the commands the app uses
+func commandInfo(w http.ResponseWriter, r *http.Request) {
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	entries, err := client.Do(ctx, client.B().CommandInfo().CommandName(appCommands...).Build()).ToArray()
+	if err != nil {
+		log.Printf("Failed to fetch command info, err = %v\n", valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to fetch command info")
+		return
+	}
+
+	commands := make([]CommandInfo, 0, len(entries))
+	for i, entry := range entries {
+		// name, arity, flags, first key, last key, step and further fields of newer versions
+		fields, err := entry.ToArray()
+		if err != nil || len(fields) < 6 {
+			// unknown commands are nil
+			log.Printf("Skipping command info of %v: %v", appCommands[i], err)
+			continue
+		}
+		var info CommandInfo
+		if info.Name, err = fields[0].ToString(); err != nil {
+			continue
+		}
+		info.Arity, _ = fields[1].AsInt64()
+		info.Flags, _ = fields[2].AsStrSlice()
+		info.FirstKey, _ = fields[3].AsInt64()
+		info.LastKey, _ = fields[4].AsInt64()
+		info.Step, _ = fields[5].AsInt64()
+		commands = append(commands, info)
+	}
+
+	writeJSON(w, http.StatusOK, commands)
+}
+
+// number of commands supported by the server
+func commandCount(w http.ResponseWriter, r *http.Request) {
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	count, err := client.Do(ctx, client.B().CommandCount().Build()).AsInt64()
+	if err != nil {
+		log.Printf("Failed to fetch command count, err = %v\n", valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to fetch command count")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"count": count})
+}
+
+// documentation of a command, requires Valkey 7 or newer
+// with RESP2 the documentation maps are flat arrays of names and values
+func commandDocs(w http.ResponseWriter, r *http.Request) {
+	command := r.URL.Query().Get("command")
+	if len(command) < 1 {
+		writeJSONError(w, http.StatusBadRequest, "query parameter command is required")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	docs, err := client.Do(ctx, client.B().CommandDocs().CommandName(command).Build()).ToAny()
+	if err != nil {
+		log.Printf("Failed to fetch docs of command %v, err = %v\n", command, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch docs of command %v", command))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, docs)
+}
+
+// OBJECT HELP only changes with the server version
+const objectHelpTTL = 5 * time.Minute
+
+type cachedObjectHelp struct {
+	lines     []string
+	fetchedAt time.Time
+}
+
+// per cluster, "" without VALKEY_CLUSTERS
+var objectHelpCache = struct {
+	sync.Mutex
+	entries map[string]cachedObjectHelp
+}{entries: make(map[string]cachedObjectHelp)}
+
+// sub-commands of OBJECT supported by the server, text with "Accept: text/plain"
+func objectHelp(w http.ResponseWriter, r *http.Request) {
+	cacheKey := ""
+	if cluster := selectedCluster(r); cluster != nil {
+		cacheKey = cluster.Name
+	}
+
+	objectHelpCache.Lock()
+	cached, ok := objectHelpCache.entries[cacheKey]
+	objectHelpCache.Unlock()
+
+	if !ok || time.Since(cached.fetchedAt) > objectHelpTTL {
+		client, err := newClient(r)
+		if err != nil {
+			log.Printf("Failed to create connection: %v", err)
+			writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+			return
+		}
+		defer client.Close()
+
+		ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+		defer cancel()
+
+		lines, err := client.Do(ctx, client.B().ObjectHelp().Build()).AsStrSlice()
+		if err != nil {
+			log.Printf("Failed to fetch OBJECT HELP, err = %v\n", valkeyErr(err))
+			writeJSONError(w, http.StatusBadGateway, "failed to fetch OBJECT HELP")
+			return
+		}
+		cached = cachedObjectHelp{lines: lines, fetchedAt: time.Now()}
+		objectHelpCache.Lock()
+		objectHelpCache.entries[cacheKey] = cached
+		objectHelpCache.Unlock()
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/plain") {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(strings.Join(cached.lines, "\n") + "\n"))
+		return
+	}
+	writeJSON(w, http.StatusOK, cached.lines)
+}
+
+// split a command line into its arguments like valkey-cli,
+// double quoted arguments support backslash escapes, single quoted ones are taken literally
+func splitCommandLine(line string) ([]string, error) {
+	args := make([]string, 0)
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, c := range line {
+		switch {
+		case escaped:
+			current.WriteRune(c)
+			escaped = false
+		case quote == '"' && c == '\\':
+			escaped = true
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(c)
+		case c == '"' || c == '\'':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(c)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// keys of a command line according to the server, e.g. ?cmd=SET mykey value
+func commandGetKeys(w http.ResponseWriter, r *http.Request) {
+	args, err := splitCommandLine(r.URL.Query().Get("cmd"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid cmd: %v", err))
+		return
+	}
+	if len(args) < 1 {
+		writeJSONError(w, http.StatusBadRequest, "query parameter cmd is required")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	keys, err := client.Do(ctx, client.B().CommandGetkeys().Command(args[0]).Arg(args[1:]...).Build()).AsStrSlice()
+	if _, ok := valkey.IsValkeyErr(err); ok {
+		// e.g. "The command has no key arguments" for PING or unknown commands
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch keys of command %v, err = %v\n", args[0], valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch keys of command %v", args[0]))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": keys})
+}
@@ -0,0 +1,611 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// upper bound for the number of results returned by the value search
+const maxSearchLimit = 100
+
+// write a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+// write a JSON error response
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// search string values by regular expression
+// this walks the whole keyspace, so it has to be enabled explicitly
+func searchKeyValues(w http.ResponseWriter, r *http.Request) {
+	if os.Getenv("VALKEY_SEARCH_ENABLED") != "true" {
+		writeJSONError(w, http.StatusNotFound, "value search is disabled")
+		return
+	}
+
+	pattern := r.URL.Query().Get("value_pattern")
+	if len(pattern) < 1 {
+		writeJSONError(w, http.StatusBadRequest, "query parameter value_pattern is required")
+		return
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := maxSearchLimit
+	if limitStr := r.URL.Query().Get("limit"); len(limitStr) > 0 {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			writeJSONError(w, http.StatusBadRequest, "query parameter limit must be a positive integer")
+			return
+		}
+		if limit > maxSearchLimit {
+			limit = maxSearchLimit
+		}
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+	matches := make([]KeyValue, 0)
+	var cursor uint64
+scan:
+	for {
+		// see scanPages for the COUNT trade-off
+		entry, err := client.Do(ctx, client.B().Scan().Cursor(cursor).Count(scanCount).Type("string").Build()).AsScanEntry()
+		if err != nil {
+			log.Printf("Failed to scan keys, err = %v\n", valkeyErr(err))
+			writeJSONError(w, http.StatusBadGateway, "failed to scan keys")
+			return
+		}
+
+		// fetch the values of the whole page in one round trip
+		cmds := make(valkey.Commands, 0, len(entry.Elements))
+		for _, key := range entry.Elements {
+			cmds = append(cmds, client.B().Get().Key(key).Build())
+		}
+		for i, resp := range client.DoMulti(ctx, cmds...) {
+			value, err := resp.ToString()
+			if err != nil {
+				// the key may have expired or changed its type since the scan
+				continue
+			}
+			if re.MatchString(value) {
+				matches = append(matches, KeyValue{Key: entry.Elements[i], Value: value})
+				if len(matches) >= limit {
+					break scan
+				}
+			}
+		}
+
+		cursor = entry.Cursor
+		if cursor == 0 {
+			break
+		}
+	}
+
+	writeJSON(w, http.StatusOK, matches)
+}
+
+// fetch the raw value of a single key
+// ?encoding=base64 returns the value base64 encoded, e.g. for binary data
+// ?download=true serves it as an attachment named after the key
+func getValue(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	encoding := r.URL.Query().Get("encoding")
+	if len(encoding) > 0 && encoding != "base64" {
+		renderError(w, r, http.StatusBadRequest, fmt.Sprintf("unsupported encoding %v", encoding))
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		renderError(w, r, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+	value, err := client.Do(ctx, client.B().Get().Key(key).Build()).AsBytes()
+	if valkey.IsValkeyNil(err) {
+		renderError(w, r, http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch value for key %v, err = %v\n", key, valkeyErr(err))
+		renderError(w, r, http.StatusBadGateway, fmt.Sprintf("failed to fetch value for key %v", key))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	disposition := "inline"
+	if r.URL.Query().Get("download") == "true" {
+		disposition = mime.FormatMediaType("attachment", map[string]string{"filename": key})
+	}
+	w.Header().Set("Content-Disposition", disposition)
+	if encoding == "base64" {
+		w.Write([]byte(base64.StdEncoding.EncodeToString(value)))
+		return
+	}
+	w.Write(value)
+}
+
+// per key metadata of the OBJECT sub-commands
+type ObjectInfo struct {
+	Encoding string `json:"encoding"`
+	// only tracked with an LRU or other non LFU maxmemory-policy
+	IdleTime *int64 `json:"idletime"`
+	RefCount int64  `json:"refcount"`
+	// only available with an LFU maxmemory-policy
+	Freq *int64 `json:"freq"`
+}
+
+// whether the maxmemory-policy is allkeys-lfu or volatile-lfu
+// CONFIG may be disabled on managed instances, which counts as no LFU
+func lfuPolicy(ctx context.Context, client ValkeyClient) bool {
+	config, err := client.Do(ctx, client.B().ConfigGet().Parameter("maxmemory-policy").Build()).AsStrMap()
+	if err != nil {
+		log.Printf("Failed to fetch maxmemory-policy, err = %v\n", valkeyErr(err))
+		return false
+	}
+	return strings.HasSuffix(config["maxmemory-policy"], "-lfu")
+}
+
+// fetch all OBJECT metadata of a key
+// IDLETIME and FREQ exclude each other, the maxmemory-policy decides which one is queried
+func fetchObjectInfo(ctx context.Context, client ValkeyClient, key string) (ObjectInfo, error) {
+	lfu := lfuPolicy(ctx, client)
+	usage := client.B().ObjectIdletime().Key(key).Build()
+	if lfu {
+		usage = client.B().ObjectFreq().Key(key).Build()
+	}
+	resps := client.DoMulti(ctx,
+		client.B().ObjectEncoding().Key(key).Build(),
+		client.B().ObjectRefcount().Key(key).Build(),
+		usage,
+	)
+
+	var info ObjectInfo
+	var err error
+	if info.Encoding, err = resps[0].ToString(); err != nil {
+		return info, err
+	}
+	if info.RefCount, err = resps[1].AsInt64(); err != nil {
+		return info, err
+	}
+	value, err := resps[2].AsInt64()
+	if err != nil {
+		return info, err
+	}
+	if lfu {
+		info.Freq = &value
+	} else {
+		info.IdleTime = &value
+	}
+	return info, nil
+}
+
+func getObjectInfo(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	info, err := fetchObjectInfo(ctx, client, key)
+	if valkey.IsValkeyNil(err) {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch object info for key %v, err = %v\n", key, valkeyErr(err))
+		if writeMovedError(w, err) {
+			return
+		}
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch object info for key %v", key))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+// decode the JSON request body into v, answers with 400 on failure
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON body: %v", err))
+		return false
+	}
+	return true
+}
+
+// move a key into another database
+func moveKeyValue(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	var body struct {
+		DestinationDB *int64 `json:"destination_db"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if body.DestinationDB == nil || *body.DestinationDB < 0 {
+		writeJSONError(w, http.StatusBadRequest, "destination_db must be a database number")
+		return
+	}
+	if *body.DestinationDB == int64(valkeyDB) {
+		writeJSONError(w, http.StatusBadRequest, "destination_db is the current database")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	// MOVE answers 0 for a missing source as well as for an existing destination
+	exists, err := client.Do(ctx, client.B().Exists().Key(key).Build()).AsInt64()
+	if err != nil {
+		log.Printf("Failed to check key %v, err = %v\n", key, valkeyErr(err))
+		if writeMovedError(w, err) {
+			return
+		}
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to check key %v", key))
+		return
+	}
+	if exists == 0 {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return
+	}
+
+	moved, err := replyInt64(client.Do(ctx, client.B().Move().Key(key).Db(*body.DestinationDB).Build()))
+	if err != nil {
+		log.Printf("Failed to move key %v to database %v, err = %v\n", key, *body.DestinationDB, valkeyErr(err))
+		if writeMovedError(w, err) {
+			return
+		}
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to move key %v", key))
+		return
+	}
+	if moved == 0 {
+		writeJSONError(w, http.StatusConflict, fmt.Sprintf("key %v already exists in database %v", key, *body.DestinationDB))
+		return
+	}
+
+	audit(ctx, client, r, "move", key, map[string]interface{}{
+		"source_db":      valkeyDB,
+		"destination_db": *body.DestinationDB,
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"moved": true})
+}
+
+// remove the TTL of a key
+func persistKeyValue(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	// 0 if the key has no TTL or does not exist
+	persisted, err := replyBool(client.Do(ctx, client.B().Persist().Key(key).Build()))
+	if err != nil {
+		log.Printf("Failed to persist key %v, err = %v\n", key, valkeyErr(err))
+		if writeMovedError(w, err) {
+			return
+		}
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to persist key %v", key))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"persisted": persisted})
+}
+
+// set an absolute or relative expiry of a key
+// with "milliseconds": true the given timestamp or duration is in milliseconds
+func expireKeyValue(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	var body struct {
+		UnixTimestamp   *int64 `json:"unix_timestamp"`
+		DurationSeconds *int64 `json:"duration_seconds"`
+		Milliseconds    bool   `json:"milliseconds"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if (body.UnixTimestamp == nil) == (body.DurationSeconds == nil) {
+		writeJSONError(w, http.StatusBadRequest, "either unix_timestamp or duration_seconds is required")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	var cmd valkey.Completed
+	switch {
+	case body.UnixTimestamp != nil && body.Milliseconds:
+		cmd = client.B().Pexpireat().Key(key).MillisecondsTimestamp(*body.UnixTimestamp).Build()
+	case body.UnixTimestamp != nil:
+		cmd = client.B().Expireat().Key(key).Timestamp(*body.UnixTimestamp).Build()
+	case body.Milliseconds:
+		cmd = client.B().Pexpire().Key(key).Milliseconds(*body.DurationSeconds).Build()
+	default:
+		cmd = client.B().Expire().Key(key).Seconds(*body.DurationSeconds).Build()
+	}
+
+	// 0 if the key does not exist
+	set, err := replyBool(client.Do(ctx, cmd))
+	if err != nil {
+		log.Printf("Failed to set expiry of key %v, err = %v\n", key, valkeyErr(err))
+		if writeMovedError(w, err) {
+			return
+		}
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to set expiry of key %v", key))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"set": set})
+}
+
+// remaining time to live of a key, -1 for keys without expiry
+// ?precision=ms queries PTTL instead of TTL
+func getTTL(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	precision := r.URL.Query().Get("precision")
+	if len(precision) > 0 && precision != "ms" {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("unsupported precision %v", precision))
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	cmd := client.B().Ttl().Key(key).Build()
+	if precision == "ms" {
+		cmd = client.B().Pttl().Key(key).Build()
+	}
+	ttl, err := client.Do(ctx, cmd).AsInt64()
+	if err != nil {
+		log.Printf("Failed to fetch TTL of key %v, err = %v\n", key, valkeyErr(err))
+		if writeMovedError(w, err) {
+			return
+		}
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch TTL of key %v", key))
+		return
+	}
+	// -2 if the key does not exist, -1 if it has no expiry
+	if ttl == -2 {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return
+	}
+
+	ttlMs := ttl * 1000
+	if precision == "ms" {
+		ttlMs = ttl
+	}
+	var expiresAt *string
+	if ttl >= 0 {
+		at := time.Now().Add(time.Duration(ttlMs) * time.Millisecond).UTC().Format(time.RFC3339)
+		expiresAt = &at
+	}
+	ttlSeconds := ttlMs / 1000
+	if ttl == -1 {
+		ttlSeconds, ttlMs = -1, -1
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ttl_seconds": ttlSeconds,
+		"ttl_ms":      ttlMs,
+		"expires_at":  expiresAt,
+	})
+}
+
+// set the time to live of a key, the value is left untouched
+func setTTL(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	var body struct {
+		TTLSeconds *int64 `json:"ttl_seconds"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if body.TTLSeconds == nil || *body.TTLSeconds < 1 {
+		writeJSONError(w, http.StatusBadRequest, "ttl_seconds must be a positive integer")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	// 0 if the key does not exist
+	set, err := client.Do(ctx, client.B().Expire().Key(key).Seconds(*body.TTLSeconds).Build()).AsBool()
+	if err != nil {
+		log.Printf("Failed to set TTL of key %v, err = %v\n", key, valkeyErr(err))
+		if writeMovedError(w, err) {
+			return
+		}
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to set TTL of key %v", key))
+		return
+	}
+	if !set {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ttl_seconds": *body.TTLSeconds})
+}
+
+// longest WAIT for replica acknowledgements, longer timeout_ms values are capped
+const maxWaitTimeout = 30 * time.Second
+
+// ?replicas=<n>&timeout_ms=<ms> of the write handlers, replicas 0 skips WAIT
+func parseWaitParams(r *http.Request) (int64, time.Duration, error) {
+	var replicas, timeoutMs int64
+	var err error
+	if replicasStr := r.URL.Query().Get("replicas"); len(replicasStr) > 0 {
+		replicas, err = strconv.ParseInt(replicasStr, 10, 64)
+		if err != nil || replicas < 0 {
+			return 0, 0, fmt.Errorf("query parameter replicas must be a non-negative integer")
+		}
+	}
+	if timeoutStr := r.URL.Query().Get("timeout_ms"); len(timeoutStr) > 0 {
+		timeoutMs, err = strconv.ParseInt(timeoutStr, 10, 64)
+		if err != nil || timeoutMs < 0 {
+			return 0, 0, fmt.Errorf("query parameter timeout_ms must be a non-negative integer")
+		}
+	}
+	if replicas < 1 {
+		return replicas, 0, nil
+	}
+	// WAIT with timeout 0 blocks until enough replicas acknowledged, holding a connection of the blocking pool
+	if timeoutMs < 1 {
+		return 0, 0, fmt.Errorf("query parameter timeout_ms must be positive with replicas")
+	}
+	return replicas, min(time.Duration(timeoutMs)*time.Millisecond, maxWaitTimeout), nil
+}
+
+// create or replace a key value pair
+// with ?replicas=<n> the response waits for the replication, answers 202 if fewer replicas acknowledged
+func createKeyValueAPI(w http.ResponseWriter, r *http.Request) {
+	replicas, timeout, err := parseWaitParams(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var body KeyValue
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if len(body.Key) < 1 {
+		writeJSONError(w, http.StatusBadRequest, "key must not be empty")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	// WAIT blocks up to the timeout on top of the commands
+	ctx, cancel := withDeadline(r.Context(), timeout+valkeyCmdTimeout)
+	defer cancel()
+
+	if replicas > 0 {
+		// WAIT takes a connection of the blocking pool
+		release, err := acquireBlockingConn(ctx, selectedCluster(r))
+		if err != nil {
+			log.Printf("Failed to set key %v, err = %v\n", body.Key, err)
+			writeJSONError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		defer release()
+	}
+
+	replicated, err := setValueAndWait(ctx, client, body.Key, body.Value, replicas, timeout)
+	if err != nil {
+		log.Printf("Failed to set key %v, err = %v\n", body.Key, valkeyErr(err))
+		if writeMovedError(w, err) {
+			return
+		}
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to set key %v", body.Key))
+		return
+	}
+
+	if replicas < 1 {
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"key": body.Key})
+		return
+	}
+	if replicated < replicas {
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{
+			"key":           body.Key,
+			"replicated_to": replicated,
+			"warning":       fmt.Sprintf("only %v of %v replicas acknowledged the write within %v", replicated, replicas, timeout),
+		})
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"key":           body.Key,
+		"replicated_to": replicated,
+	})
+}
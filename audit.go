@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// number of entries kept in the audit log list
+const auditLogSize = 1000
+
+type AuditEntry struct {
+	Time       string                 `json:"time"`
+	Action     string                 `json:"action"`
+	Key        string                 `json:"key,omitempty"`
+	RemoteAddr string                 `json:"remote_addr"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+}
+
+// record an operation in the log and in the capped audit list of the app
+// a failure is only logged, it never fails the operation itself
+func audit(ctx context.Context, client ValkeyClient, r *http.Request, action string, key string, details map[string]interface{}) {
+	entry := AuditEntry{
+		Time:       time.Now().UTC().Format(time.RFC3339),
+		Action:     action,
+		Key:        key,
+		RemoteAddr: clientIP(r, trustProxy),
+		Details:    details,
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Failed to encode audit entry: %v", err)
+		return
+	}
+	log.Printf("Audit: %s\n", data)
+
+	auditKey := internalKey("audit")
+	for _, resp := range client.DoMulti(ctx,
+		client.B().Lpush().Key(auditKey).Element(string(data)).Build(),
+		client.B().Ltrim().Key(auditKey).Start(0).Stop(auditLogSize-1).Build(),
+	) {
+		if err := resp.Error(); err != nil {
+			log.Printf("Failed to store audit entry, err = %v\n", valkeyErr(err))
+			return
+		}
+	}
+}
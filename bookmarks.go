@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// bookmarks are shared by all users of the app, there is no user identity
+func bookmarksKey() string {
+	return internalKey("bookmarks")
+}
+
+// set of the bookmarked keys
+func fetchBookmarks(ctx context.Context, client ValkeyClient) (map[string]bool, error) {
+	keys, err := client.Do(ctx, client.B().Smembers().Key(bookmarksKey()).Build()).AsStrSlice()
+	if err != nil {
+		return nil, err
+	}
+	bookmarks := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		bookmarks[key] = true
+	}
+	return bookmarks, nil
+}
+
+// POST bookmarks a key, DELETE removes the bookmark
+func bookmarkKeyValue(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	bookmarked := r.Method == http.MethodPost
+	cmd := client.B().Sadd().Key(bookmarksKey()).Member(key).Build()
+	if !bookmarked {
+		cmd = client.B().Srem().Key(bookmarksKey()).Member(key).Build()
+	}
+	err = client.Do(ctx, cmd).Error()
+	if err != nil {
+		log.Printf("Failed to update bookmark of key %v, err = %v\n", key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to update bookmark of key %v", key))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"bookmarked": bookmarked})
+}
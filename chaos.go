@@ -0,0 +1,51 @@
+//go:build chaos
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/valkey-io/valkey-go"
+	// valkey-go has no public constructor of failed results, the mock package is only linked into chaos builds
+	"github.com/valkey-io/valkey-go/mock"
+)
+
+// VALKEY_CHAOS_RATE only takes effect in builds with -tags chaos
+const chaosBuild = true
+
+var errChaos = errors.New("chaos: injected failure")
+
+func withChaos(client ValkeyClient, rate float64) ValkeyClient {
+	return chaosClient{ValkeyClient: client, rate: rate}
+}
+
+// fails a random share of the commands for resilience testing
+type chaosClient struct {
+	ValkeyClient
+	rate float64
+}
+
+func (c chaosClient) Do(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+	if rand.Float64() < c.rate {
+		log.Printf("Chaos, failing: %v\n", strings.Join(cmd.Commands(), " "))
+		return mock.ErrorResult(errChaos)
+	}
+	return c.ValkeyClient.Do(ctx, cmd)
+}
+
+// a batch fails as a whole, like a pipeline on a broken connection
+func (c chaosClient) DoMulti(ctx context.Context, multi ...valkey.Completed) []valkey.ValkeyResult {
+	if rand.Float64() >= c.rate {
+		return c.ValkeyClient.DoMulti(ctx, multi...)
+	}
+	resps := make([]valkey.ValkeyResult, len(multi))
+	for i, cmd := range multi {
+		log.Printf("Chaos, failing: %v\n", strings.Join(cmd.Commands(), " "))
+		resps[i] = mock.ErrorResult(errChaos)
+	}
+	return resps
+}
@@ -0,0 +1,10 @@
+//go:build !chaos
+
+package main
+
+// VALKEY_CHAOS_RATE only takes effect in builds with -tags chaos
+const chaosBuild = false
+
+func withChaos(client ValkeyClient, rate float64) ValkeyClient {
+	return client
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// subset of valkey.Client used by the handlers, allows to inject MockValkeyClient
+type ValkeyClient interface {
+	B() valkey.Builder
+	Do(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult
+	DoMulti(ctx context.Context, multi ...valkey.Completed) []valkey.ValkeyResult
+	Dedicated(fn func(valkey.DedicatedClient) error) error
+	Receive(ctx context.Context, subscribe valkey.Completed, fn func(valkey.PubSubMessage)) error
+	Close()
+}
+
+// see VALKEY_DRY_RUN
+var dryRun bool
+
+// UNLINK frees the memory of deleted keys in a background thread, see VALKEY_USE_UNLINK
+var useUnlink = true
+
+// delete keys with UNLINK, or with DEL for servers older than Redis 4
+func deleteCommand(client ValkeyClient, keys ...string) valkey.Completed {
+	if useUnlink {
+		return client.B().Unlink().Key(keys...).Build()
+	}
+	return client.B().Del().Key(keys...).Build()
+}
+
+// share of commands failed on purpose, see VALKEY_CHAOS_RATE and chaos.go
+var chaosRate float64
+
+// client factory of the handlers, connects to the cluster selected by the request
+// without a request, e.g. in background jobs, the default cluster is used
+var newClient = func(r *http.Request) (ValkeyClient, error) {
+	return newClusterClient(selectedCluster(r))
+}
+
+// shared client of the cluster with the metrics, chaos and dry run wrappers
+func newClusterClient(cluster *ClusterConfig) (ValkeyClient, error) {
+	client, err := clusterClient(cluster)
+	if err != nil {
+		setLastError(err)
+		return nil, err
+	}
+	var wrapped ValkeyClient = newMetricsClient(client)
+	if chaosRate > 0 {
+		wrapped = withChaos(wrapped, chaosRate)
+	}
+	if dryRun {
+		wrapped = dryRunClient{wrapped}
+	}
+	return wrapped, nil
+}
+
+// one client per cluster, keyed by the cluster name, "" without VALKEY_CLUSTERS
+// failed connections are not kept, the next request tries again
+var (
+	sharedClientsMu sync.Mutex
+	sharedClients   = make(map[string]*sharedClient)
+)
+
+func clusterClient(cluster *ClusterConfig) (*sharedClient, error) {
+	name := ""
+	if cluster != nil {
+		name = cluster.Name
+	}
+
+	sharedClientsMu.Lock()
+	shared, ok := sharedClients[name]
+	sharedClientsMu.Unlock()
+	if ok {
+		return shared, nil
+	}
+
+	// dial without the lock, a cluster that is down must not hold up the others
+	client, err := NewClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	sharedClientsMu.Lock()
+	defer sharedClientsMu.Unlock()
+	if shared, ok := sharedClients[name]; ok {
+		// a concurrent request connected first
+		client.Close()
+		return shared, nil
+	}
+	sharedClients[name] = newSharedClient(client)
+	return sharedClients[name], nil
+}
+
+// protocol version negotiated at startup, 0 if unknown
+var protocolVersion int64
+
+// log the protocol version negotiated with Valkey, see /version
+func logProtocolVersion() {
+	client, err := newClient(nil)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(context.Background(), valkeyCmdTimeout)
+	defer cancel()
+
+	// HELLO without a version reports the current connection without renegotiating
+	hello, err := client.Do(ctx, client.B().Hello().Build()).AsMap()
+	if err != nil {
+		log.Printf("Failed to fetch protocol version, err = %v\n", valkeyErr(err))
+		return
+	}
+	protoMessage := hello["proto"]
+	proto, err := protoMessage.AsInt64()
+	if err != nil {
+		log.Printf("Failed to read protocol version, err = %v\n", err)
+		return
+	}
+	log.Printf("Negotiated RESP%v with Valkey\n", proto)
+	protocolVersion = proto
+}
+
+// CONFIG SET notify-keyspace-events, a failure only means the notifications stay as configured
+func configureKeyspaceEvents(cluster *ClusterConfig, events string) {
+	client, err := newClusterClient(cluster)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(context.Background(), valkeyCmdTimeout)
+	defer cancel()
+
+	err = client.Do(ctx, client.B().ConfigSet().ParameterValue().ParameterValue("notify-keyspace-events", events).Build()).Error()
+	if err != nil {
+		// e.g. CONFIG is renamed or forbidden by an ACL on managed instances
+		log.Printf("Warning: failed to set notify-keyspace-events to %q, err = %v\n", events, valkeyErr(err))
+		return
+	}
+	log.Printf("Set notify-keyspace-events to %q\n", events)
+}
+
+// logs commands that are not flagged read-only instead of executing them
+// the skipped commands answer a reply without a value, the handlers read it as done, see replyInt64
+type dryRunClient struct {
+	ValkeyClient
+}
+
+func (c dryRunClient) Do(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+	if cmd.IsReadOnly() {
+		return c.ValkeyClient.Do(ctx, cmd)
+	}
+	log.Printf("Dry run, skipping: %v\n", strings.Join(cmd.Commands(), " "))
+	return valkey.ValkeyResult{}
+}
+
+// the batch is sent or skipped as a whole, pipelines like SET and WAIT rely on a single connection
+func (c dryRunClient) DoMulti(ctx context.Context, multi ...valkey.Completed) []valkey.ValkeyResult {
+	readOnly := true
+	for _, cmd := range multi {
+		readOnly = readOnly && cmd.IsReadOnly()
+	}
+	if readOnly {
+		return c.ValkeyClient.DoMulti(ctx, multi...)
+	}
+	for _, cmd := range multi {
+		log.Printf("Dry run, skipping: %v\n", strings.Join(cmd.Commands(), " "))
+	}
+	return make([]valkey.ValkeyResult, len(multi))
+}
+
+// a write skipped by dryRunClient has a reply without a value, which no As* method can read
+func skippedByDryRun(result valkey.ValkeyResult) bool {
+	return dryRun && reflect.ValueOf(result).IsZero()
+}
+
+// replies of writes, a skipped write reads as done: one affected key or element, true, "OK" or no elements
+func replyInt64(result valkey.ValkeyResult) (int64, error) {
+	if skippedByDryRun(result) {
+		return 1, nil
+	}
+	return result.AsInt64()
+}
+
+func replyBool(result valkey.ValkeyResult) (bool, error) {
+	if skippedByDryRun(result) {
+		return true, nil
+	}
+	return result.AsBool()
+}
+
+func replyString(result valkey.ValkeyResult) (string, error) {
+	if skippedByDryRun(result) {
+		return "OK", nil
+	}
+	return result.ToString()
+}
+
+func replyStrSlice(result valkey.ValkeyResult) ([]string, error) {
+	if skippedByDryRun(result) {
+		return []string{}, nil
+	}
+	return result.AsStrSlice()
+}
+
+// connection closed once it reaches its maximum age
+type expiringConn struct {
+	net.Conn
+	timer *time.Timer
+}
+
+func (c *expiringConn) Close() error {
+	c.timer.Stop()
+	return c.Conn.Close()
+}
+
+// exponential backoff from minBackoff up to maxBackoff, no retry after maxRetries attempts
+// valkey-go only retries read-only commands, e.g. GET and SCAN, after network errors
+func retryDelay(maxRetries int, minBackoff time.Duration, maxBackoff time.Duration) valkey.RetryDelayFn {
+	return func(attempts int, cmd valkey.Completed, err error) time.Duration {
+		if attempts > maxRetries {
+			log.Printf("Giving up %v after %v attempts: %v\n", commandName(cmd), attempts, err)
+			return -1
+		}
+		delay := min(maxBackoff, minBackoff<<min(attempts-1, 30))
+		log.Printf("Retrying %v, attempt %v of %v in %v: %v\n", commandName(cmd), attempts, maxRetries, delay, err)
+		return delay
+	}
+}
+
+// dial connections which are closed after maxLifetime
+// valkey-go has no lifetime option, but redials a closed connection on its next use
+func dialWithMaxLifetime(maxLifetime time.Duration) func(string, *net.Dialer, *tls.Config) (net.Conn, error) {
+	return func(addr string, dialer *net.Dialer, tlsConfig *tls.Config) (net.Conn, error) {
+		var conn net.Conn
+		var err error
+		if tlsConfig != nil {
+			conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+		} else {
+			conn, err = dialer.Dial("tcp", addr)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		expiring := &expiringConn{Conn: conn}
+		expiring.timer = time.AfterFunc(maxLifetime, func() {
+			log.Printf("Closing connection to %v after %v\n", addr, maxLifetime)
+			conn.Close()
+		})
+		return expiring, nil
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// counts the batches reaching the wrapped client
+type batchRecorder struct {
+	ValkeyClient
+	batches [][]string
+}
+
+func (c *batchRecorder) DoMulti(ctx context.Context, multi ...valkey.Completed) []valkey.ValkeyResult {
+	names := make([]string, len(multi))
+	for i, cmd := range multi {
+		names[i] = commandName(cmd)
+	}
+	c.batches = append(c.batches, names)
+	return c.ValkeyClient.DoMulti(ctx, multi...)
+}
+
+func TestDryRunClientDoMulti(t *testing.T) {
+	mock := NewMockValkeyClient()
+	mock.store["greeting"] = "hello"
+
+	tests := []struct {
+		name    string
+		multi   func(b valkey.Builder) valkey.Commands
+		batches int
+	}{
+		{
+			name: "read-only batch is sent as one",
+			multi: func(b valkey.Builder) valkey.Commands {
+				return valkey.Commands{b.Get().Key("greeting").Build(), b.Type().Key("greeting").Build()}
+			},
+			batches: 1,
+		},
+		{
+			name: "batch with a write is skipped as a whole",
+			multi: func(b valkey.Builder) valkey.Commands {
+				return valkey.Commands{b.Get().Key("greeting").Build(), b.Set().Key("greeting").Value("bye").Build()}
+			},
+			batches: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &batchRecorder{ValkeyClient: mock}
+			client := dryRunClient{recorder}
+
+			multi := tt.multi(client.B())
+			resps := client.DoMulti(context.Background(), multi...)
+
+			if len(resps) != len(multi) {
+				t.Fatalf("got %v results for %v commands", len(resps), len(multi))
+			}
+			if len(recorder.batches) != tt.batches {
+				t.Errorf("batches = %v, want %v", recorder.batches, tt.batches)
+			}
+			if mock.store["greeting"] != "hello" {
+				t.Errorf("greeting = %q, the dry run modified data", mock.store["greeting"])
+			}
+		})
+	}
+}
+
+func TestDryRunWriteEndpoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		handler http.HandlerFunc
+		status  int
+	}{
+		{name: "create with WAIT", method: http.MethodPost, path: "/api/v1/key-values?replicas=1&timeout_ms=10", body: `{"key":"greeting","value":"hi"}`, handler: createKeyValueAPI, status: http.StatusCreated},
+		{name: "delete", method: http.MethodDelete, path: "/api/v1/key-values/greeting", handler: deleteKeyValue, status: http.StatusOK},
+		{name: "persist", method: http.MethodPost, path: "/api/v1/key-values/greeting/persist", handler: persistKeyValue, status: http.StatusOK},
+		{name: "expire", method: http.MethodPost, path: "/api/v1/key-values/greeting/expire", body: `{"duration_seconds":60}`, handler: expireKeyValue, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := useMockClient(t, map[string]string{"greeting": "hello"})
+			newClient = func(r *http.Request) (ValkeyClient, error) { return dryRunClient{mock}, nil }
+			dryRun = true
+			t.Cleanup(func() { dryRun = false })
+
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.SetPathValue("key", "greeting")
+			w := httptest.NewRecorder()
+			tt.handler(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %v, want %v, body = %v", w.Code, tt.status, w.Body.String())
+			}
+			if mock.store["greeting"] != "hello" {
+				t.Errorf("store = %v, want the value unchanged", mock.store)
+			}
+		})
+	}
+}
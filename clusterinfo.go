@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// fields of CLUSTER INFO
+type ClusterInfo struct {
+	ClusterState         string `json:"cluster_state"`
+	ClusterSlotsAssigned int64  `json:"cluster_slots_assigned"`
+	ClusterSlotsOk       int64  `json:"cluster_slots_ok"`
+	ClusterSlotsPfail    int64  `json:"cluster_slots_pfail"`
+	ClusterSlotsFail     int64  `json:"cluster_slots_fail"`
+	ClusterKnownNodes    int64  `json:"cluster_known_nodes"`
+	ClusterSize          int64  `json:"cluster_size"`
+	ClusterCurrentEpoch  int64  `json:"cluster_current_epoch"`
+	ClusterMyEpoch       int64  `json:"cluster_my_epoch"`
+}
+
+// a line of CLUSTER NODES
+type ClusterNode struct {
+	ID          string   `json:"id"`
+	Address     string   `json:"address"`
+	Flags       []string `json:"flags"`
+	Master      string   `json:"master,omitempty"`
+	PingSent    int64    `json:"ping_sent"`
+	PongRecv    int64    `json:"pong_recv"`
+	ConfigEpoch int64    `json:"config_epoch"`
+	LinkState   string   `json:"link_state"`
+	// ranges like "0-5460" and single slots
+	Slots []string `json:"slots"`
+}
+
+// standalone instances answer "ERR This instance has cluster support disabled"
+func isClusterDisabled(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "cluster support disabled")
+}
+
+func fetchClusterInfo(ctx context.Context, client ValkeyClient) (ClusterInfo, error) {
+	raw, err := client.Do(ctx, client.B().ClusterInfo().Build()).ToString()
+	if err != nil {
+		return ClusterInfo{}, err
+	}
+	fields := parseInfo(raw)
+	info := ClusterInfo{ClusterState: fields["cluster_state"]}
+	// missing fields stay 0
+	info.ClusterSlotsAssigned, _ = strconv.ParseInt(fields["cluster_slots_assigned"], 10, 64)
+	info.ClusterSlotsOk, _ = strconv.ParseInt(fields["cluster_slots_ok"], 10, 64)
+	info.ClusterSlotsPfail, _ = strconv.ParseInt(fields["cluster_slots_pfail"], 10, 64)
+	info.ClusterSlotsFail, _ = strconv.ParseInt(fields["cluster_slots_fail"], 10, 64)
+	info.ClusterKnownNodes, _ = strconv.ParseInt(fields["cluster_known_nodes"], 10, 64)
+	info.ClusterSize, _ = strconv.ParseInt(fields["cluster_size"], 10, 64)
+	info.ClusterCurrentEpoch, _ = strconv.ParseInt(fields["cluster_current_epoch"], 10, 64)
+	info.ClusterMyEpoch, _ = strconv.ParseInt(fields["cluster_my_epoch"], 10, 64)
+	return info, nil
+}
+
+// <id> <ip:port@cport> <flags> <master> <ping-sent> <pong-recv> <config-epoch> <link-state> <slot> ...
+func parseClusterNodes(raw string) []ClusterNode {
+	nodes := make([]ClusterNode, 0)
+	for _, line := range strings.Split(raw, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 8 {
+			continue
+		}
+		node := ClusterNode{
+			ID:        fields[0],
+			Address:   fields[1],
+			Flags:     strings.Split(fields[2], ","),
+			LinkState: fields[7],
+			Slots:     fields[8:],
+		}
+		// primaries have "-" as master
+		if fields[3] != "-" {
+			node.Master = fields[3]
+		}
+		node.PingSent, _ = strconv.ParseInt(fields[4], 10, 64)
+		node.PongRecv, _ = strconv.ParseInt(fields[5], 10, 64)
+		node.ConfigEpoch, _ = strconv.ParseInt(fields[6], 10, 64)
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func clusterInfo(w http.ResponseWriter, r *http.Request) {
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	info, err := fetchClusterInfo(ctx, client)
+	if isClusterDisabled(err) {
+		writeJSONError(w, http.StatusBadRequest, "the instance runs without cluster mode")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch cluster info, err = %v\n", valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to fetch cluster info")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+func clusterNodes(w http.ResponseWriter, r *http.Request) {
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	raw, err := client.Do(ctx, client.B().ClusterNodes().Build()).ToString()
+	if isClusterDisabled(err) {
+		writeJSONError(w, http.StatusBadRequest, "the instance runs without cluster mode")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch cluster nodes, err = %v\n", valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to fetch cluster nodes")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, parseClusterNodes(raw))
+}
+
+// surface MOVED replies as 502 with the slot and its node, see VALKEY_DISABLE_MOVED_REDIRECT
+// the cluster client follows them transparently, so they only reach the handlers with redirects disabled
+func writeMovedError(w http.ResponseWriter, err error) bool {
+	valkeyErr, ok := valkey.IsValkeyErr(err)
+	if !ok {
+		return false
+	}
+	if _, moved := valkeyErr.IsMoved(); !moved {
+		return false
+	}
+	// MOVED <slot> <host:port>
+	fields := strings.Fields(valkeyErr.Error())
+	if len(fields) < 3 {
+		return false
+	}
+	slot, _ := strconv.ParseInt(fields[1], 10, 64)
+	writeJSON(w, http.StatusBadGateway, map[string]interface{}{"error": "MOVED", "slot": slot, "target": fields[2]})
+	return true
+}
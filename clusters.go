@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// a Valkey cluster selectable in the UI, see VALKEY_CLUSTERS
+type ClusterConfig struct {
+	Name          string  `json:"name"`
+	Host          string  `json:"host"`
+	Port          int     `json:"port"`
+	Username      string  `json:"username"`
+	Password      string  `json:"password"`
+	CaCertificate *string `json:"cacrt"`
+}
+
+func (c ClusterConfig) credentials() ValkeyCredentials {
+	return ValkeyCredentials{
+		Host:          c.Host,
+		CaCertificate: c.CaCertificate,
+		Valkey: ValkeyDetails{
+			Password: c.Password,
+			Port:     c.Port,
+			Username: c.Username,
+		},
+	}
+}
+
+// configured clusters, empty if VALKEY_CLUSTERS is not set
+var clusters []ClusterConfig
+
+// name of the cookie holding the selected cluster
+const clusterCookie = "cluster"
+
+// key of the cookie signatures, see COOKIE_SECRET
+var cookieSecret []byte
+
+// read VALKEY_CLUSTERS and COOKIE_SECRET
+func initClusters() error {
+	clustersStr := os.Getenv("VALKEY_CLUSTERS")
+	if len(clustersStr) < 1 {
+		return nil
+	}
+	err := json.Unmarshal([]byte(clustersStr), &clusters)
+	if err != nil {
+		return fmt.Errorf("invalid VALKEY_CLUSTERS: %w", err)
+	}
+	names := make(map[string]bool, len(clusters))
+	for _, cluster := range clusters {
+		if len(cluster.Name) < 1 || len(cluster.Host) < 1 {
+			return fmt.Errorf("invalid VALKEY_CLUSTERS: every cluster requires a name and a host")
+		}
+		if names[cluster.Name] {
+			return fmt.Errorf("invalid VALKEY_CLUSTERS: duplicate cluster name %v", cluster.Name)
+		}
+		names[cluster.Name] = true
+	}
+
+	cookieSecret = []byte(os.Getenv("COOKIE_SECRET"))
+	if len(cookieSecret) < 1 {
+		// the cluster selection is lost on restart
+		log.Printf("COOKIE_SECRET not set, using a random secret\n")
+		cookieSecret = make([]byte, 32)
+		if _, err := rand.Read(cookieSecret); err != nil {
+			return err
+		}
+	}
+	log.Printf("Configured %v clusters\n", len(clusters))
+	return nil
+}
+
+func signClusterName(name string) string {
+	mac := hmac.New(sha256.New, cookieSecret)
+	mac.Write([]byte(name))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func findCluster(name string) *ClusterConfig {
+	for i := range clusters {
+		if clusters[i].Name == name {
+			return &clusters[i]
+		}
+	}
+	return nil
+}
+
+// cluster of the request, the first configured cluster unless another one is selected
+// nil without VALKEY_CLUSTERS or a request
+func selectedCluster(r *http.Request) *ClusterConfig {
+	if len(clusters) < 1 {
+		return nil
+	}
+	if r == nil {
+		return &clusters[0]
+	}
+	cookie, err := r.Cookie(clusterCookie)
+	if err != nil {
+		return &clusters[0]
+	}
+	// the signature contains no dots, cluster names may
+	i := strings.LastIndex(cookie.Value, ".")
+	if i < 0 {
+		return &clusters[0]
+	}
+	name, signature := cookie.Value[:i], cookie.Value[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(signClusterName(name))) {
+		return &clusters[0]
+	}
+	if cluster := findCluster(name); cluster != nil {
+		return cluster
+	}
+	return &clusters[0]
+}
+
+// names of the configured clusters and the selected one
+func getClusters(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(clusters))
+	for _, cluster := range clusters {
+		names = append(names, cluster.Name)
+	}
+	selected := ""
+	if cluster := selectedCluster(r); cluster != nil {
+		selected = cluster.Name
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"clusters": names,
+		"selected": selected,
+	})
+}
+
+// store the selected cluster in a signed cookie and go back to the previous page
+func selectCluster(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	name := r.PostFormValue("cluster")
+	if findCluster(name) == nil {
+		renderError(w, r, http.StatusBadRequest, fmt.Sprintf("unknown cluster %v", name))
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     clusterCookie,
+		Value:    name + "." + signClusterName(name),
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	// only the path of the referer, never redirect to another host
+	target := "/"
+	if referer, err := url.Parse(r.Referer()); err == nil && len(referer.Path) > 0 {
+		target = referer.RequestURI()
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// prefix of the keys the app stores for itself, see VALKEY_INTERNAL_PREFIX
+var internalPrefix = "__a9s__"
+
+// name of an app internal key, e.g. internalKey("bookmarks") is "__a9s__:bookmarks"
+// user managed key value pairs never get the prefix
+func internalKey(parts ...string) string {
+	return internalPrefix + ":" + strings.Join(parts, ":")
+}
+
+func isInternalKey(key string) bool {
+	return strings.HasPrefix(key, internalPrefix+":")
+}
+
+// read a duration from the environment, e.g. "1m30s"
+// plain numbers are taken as seconds
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if len(value) < 1 {
+		return fallback
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration %v=%v, using %v: %v", name, value, fallback, err)
+		return fallback
+	}
+	return d
+}
+
+// read a non-negative integer from the environment
+func intFromEnv(name string, fallback int) int {
+	value := os.Getenv(name)
+	if len(value) < 1 {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		log.Printf("Invalid %v=%v, using %v\n", name, value, fallback)
+		return fallback
+	}
+	return parsed
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// deadline of the reset, the request may end it earlier
+const resetTimeout = 5 * time.Second
+
+// RESET a dedicated connection of the blocking pool, set it up again and check it with PING
+// the pipelined connections of the client, which carry the commands of the other requests, are not
+// touched, valkey-go closes and redials those after errors by itself. RESET also drops the
+// authentication, the protocol and the database, so the reset shows that the setup of a connection
+// still works, e.g. after the credentials were rotated
+func resetConnection(ctx context.Context, client ValkeyClient, cluster *ClusterConfig, reason string) (string, error) {
+	log.Printf("Sending RESET: %v\n", reason)
+	credentials, err := clusterCredentials(cluster)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := withDeadline(ctx, resetTimeout)
+	defer cancel()
+
+	// the dedicated connection comes from the blocking pool
+	release, err := acquireBlockingConn(ctx, cluster)
+	if err != nil {
+		log.Printf("Failed to reset connection, err = %v\n", err)
+		return "", err
+	}
+	defer release()
+
+	var pong string
+	err = client.Dedicated(func(conn valkey.DedicatedClient) error {
+		if err := conn.Do(ctx, conn.B().Reset().Build()).Error(); err != nil {
+			return err
+		}
+
+		proto := protocolVersion
+		if proto == 0 {
+			proto = 3
+		}
+		hello := conn.B().Hello().Protover(proto)
+		var setup valkey.Commands
+		if len(credentials.Valkey.Password) > 0 {
+			username := credentials.Valkey.Username
+			if len(username) == 0 {
+				username = "default"
+			}
+			setup = append(setup, hello.Auth(username, credentials.Valkey.Password).Build())
+		} else {
+			setup = append(setup, hello.Build())
+		}
+		if valkeyDB != 0 {
+			setup = append(setup, conn.B().Select().Index(int64(valkeyDB)).Build())
+		}
+		setup = append(setup, conn.B().Ping().Build())
+
+		resps := conn.DoMulti(ctx, setup...)
+		for _, resp := range resps {
+			if err := resp.Error(); err != nil {
+				return err
+			}
+		}
+		var err error
+		pong, err = resps[len(resps)-1].ToString()
+		return err
+	})
+	if err != nil {
+		log.Printf("Failed to reset connection, err = %v\n", valkeyErr(err))
+		return "", err
+	}
+	return pong, nil
+}
+
+// reset a connection and check the same one with PING
+func adminResetConnection(w http.ResponseWriter, r *http.Request) {
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	pong, err := resetConnection(r.Context(), client, selectedCluster(r), "requested by /admin/reset-connection")
+	if errors.Is(err, errPoolTimeout) {
+		writeJSONError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadGateway, "failed to reset connection")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"reset": true, "ping": pong})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAdminResetConnection(t *testing.T) {
+	t.Setenv("VCAP_SERVICES", "")
+	t.Setenv("VALKEY_HOST", "localhost")
+	t.Setenv("VALKEY_PORT", "6379")
+	t.Setenv("VALKEY_USERNAME", "app")
+	t.Setenv("VALKEY_PASSWORD", "secret")
+
+	tests := []struct {
+		name     string
+		db       int
+		protocol int64
+		want     []string
+	}{
+		{
+			name:     "default database",
+			protocol: 3,
+			want:     []string{"RESET", "HELLO 3 AUTH app secret", "PING"},
+		},
+		{
+			name:     "selected database over RESP2",
+			db:       2,
+			protocol: 2,
+			want:     []string{"RESET", "HELLO 2 AUTH app secret", "SELECT 2", "PING"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousDB, previousProtocol := valkeyDB, protocolVersion
+			valkeyDB, protocolVersion = tt.db, tt.protocol
+			t.Cleanup(func() { valkeyDB, protocolVersion = previousDB, previousProtocol })
+			client := useMockClient(t, nil)
+
+			w := httptest.NewRecorder()
+			adminResetConnection(w, httptest.NewRequest(http.MethodPost, "/admin/reset-connection", nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %v, want %v", w.Code, http.StatusOK)
+			}
+			if !strings.Contains(w.Body.String(), `"ping":"PONG"`) {
+				t.Errorf("body = %v, want the PONG of the reset connection", w.Body.String())
+			}
+			// RESET and PING go over the same connection
+			if len(client.dedicated) != 1 {
+				t.Fatalf("dedicated connections = %v, want 1", len(client.dedicated))
+			}
+			if got := client.dedicated[0]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commands = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
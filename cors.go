@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+)
+
+// origins allowed to call the API from a browser, see CORS_ALLOWED_ORIGINS
+var corsAllowedOrigins []string
+
+func loadCORSOrigins() []string {
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); len(origin) > 0 {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+func corsOriginAllowed(origin string) bool {
+	for _, allowed := range corsAllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// add the CORS headers to the responses of /api/ for allowed origins
+// and answer their preflight requests, which the method patterns of the mux would reject
+func withCORS(next http.Handler) http.Handler {
+	if len(corsAllowedOrigins) < 1 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if len(origin) < 1 || !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		if !corsOriginAllowed(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Max-Age", "600")
+		if r.Method == http.MethodOptions && len(r.Header.Get("Access-Control-Request-Method")) > 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// longest DEBUG SLEEP, the whole server stops answering meanwhile
+const debugSleepMaxSeconds = 10
+
+// DEBUG OBJECT of a key, e.g. encoding, refcount and serialized length
+func debugObject(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	result, err := client.Do(ctx, client.B().Arbitrary("DEBUG", "OBJECT").Keys(key).ReadOnly()).ToString()
+	if err != nil && strings.Contains(err.Error(), "no such key") {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to debug key %v, err = %v\n", key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to debug key %v", key))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"result": result})
+}
+
+// DEBUG RELOAD saves and reloads the dataset, the server blocks until it is done
+func debugReload(w http.ResponseWriter, r *http.Request) {
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	result, err := replyString(client.Do(ctx, client.B().Arbitrary("DEBUG", "RELOAD").Build()))
+	if err != nil {
+		log.Printf("Failed to reload the dataset, err = %v\n", valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to reload the dataset")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"result": result})
+}
+
+// DEBUG SLEEP to simulate a hanging server, only with ENABLE_DEBUG_SLEEP=true
+func debugSleep(w http.ResponseWriter, r *http.Request) {
+	if os.Getenv("ENABLE_DEBUG_SLEEP") != "true" {
+		writeJSONError(w, http.StatusNotFound, "DEBUG SLEEP is disabled, ENABLE_DEBUG_SLEEP not set")
+		return
+	}
+	seconds := 1.0
+	if secondsStr := r.URL.Query().Get("seconds"); len(secondsStr) > 0 {
+		var err error
+		seconds, err = strconv.ParseFloat(secondsStr, 64)
+		if err != nil || seconds <= 0 || seconds > debugSleepMaxSeconds {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("seconds must be above 0 and at most %v", debugSleepMaxSeconds))
+			return
+		}
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), time.Duration(seconds*float64(time.Second))+valkeyCmdTimeout)
+	defer cancel()
+
+	secondsArg := strconv.FormatFloat(seconds, 'f', -1, 64)
+	result, err := replyString(client.Do(ctx, client.B().Arbitrary("DEBUG", "SLEEP", secondsArg).Blocking()))
+	if err != nil {
+		log.Printf("Failed to sleep %v seconds, err = %v\n", seconds, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to sleep")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"result": result})
+}
+
+// DEBUG CHANGE-REPL-ID forces the replicas into a full sync, only with ENABLE_DEBUG_COMMANDS=true
+// answers the replication IDs after the change
+func debugChangeReplID(w http.ResponseWriter, r *http.Request) {
+	if os.Getenv("ENABLE_DEBUG_COMMANDS") != "true" {
+		writeJSONError(w, http.StatusNotFound, "DEBUG CHANGE-REPL-ID is disabled, ENABLE_DEBUG_COMMANDS not set")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	err = client.Do(ctx, client.B().Arbitrary("DEBUG", "CHANGE-REPL-ID").Build()).Error()
+	if err != nil {
+		log.Printf("Failed to change the replication ID, err = %v\n", valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to change the replication ID")
+		return
+	}
+
+	raw, err := client.Do(ctx, client.B().Info().Section("replication").Build()).ToString()
+	if err != nil {
+		log.Printf("Failed to fetch replication info, err = %v\n", valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "replication ID changed, failed to fetch the new one")
+		return
+	}
+	fields := parseInfo(raw)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"master_replid":  fields["master_replid"],
+		"master_replid2": fields["master_replid2"],
+	})
+}
+
+// DEBUG QUICKLIST-PACKED-THRESHOLD to reproduce list encoding transitions with small elements
+// only with ENABLE_DEBUG_COMMANDS=true and never on Cloud Foundry, answers the list-max-listpack-size before the change
+func debugQuicklistThreshold(w http.ResponseWriter, r *http.Request) {
+	if os.Getenv("ENABLE_DEBUG_COMMANDS") != "true" {
+		writeJSONError(w, http.StatusNotFound, "DEBUG QUICKLIST-PACKED-THRESHOLD is disabled, ENABLE_DEBUG_COMMANDS not set")
+		return
+	}
+	// the threshold applies to the whole server, which is shared on Cloud Foundry
+	if len(os.Getenv("VCAP_SERVICES")) > 0 {
+		writeJSONError(w, http.StatusForbidden, "DEBUG QUICKLIST-PACKED-THRESHOLD is only allowed in test environments")
+		return
+	}
+	bytes, err := strconv.ParseInt(r.URL.Query().Get("bytes"), 10, 64)
+	if err != nil || bytes < 1 {
+		writeJSONError(w, http.StatusBadRequest, "query parameter bytes must be a positive integer")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	// the change is still useful without the previous value
+	var previous *string
+	config, err := client.Do(ctx, client.B().ConfigGet().Parameter("list-max-listpack-size").Build()).AsStrMap()
+	if err != nil {
+		log.Printf("Failed to fetch list-max-listpack-size, err = %v\n", valkeyErr(err))
+	} else if value, ok := config["list-max-listpack-size"]; ok {
+		previous = &value
+	}
+
+	bytesArg := strconv.FormatInt(bytes, 10)
+	result, err := replyString(client.Do(ctx, client.B().Arbitrary("DEBUG", "QUICKLIST-PACKED-THRESHOLD", bytesArg).Build()))
+	if err != nil {
+		log.Printf("Failed to set the quicklist packed threshold to %v, err = %v\n", bytes, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to set the quicklist packed threshold")
+		return
+	}
+	log.Printf("Set the quicklist packed threshold to %v bytes\n", bytes)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"result": result, "previous_list_max_listpack_size": previous})
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// metadata and value of a single key
+type KeyDetails struct {
+	Key  string `json:"key"`
+	Type string `json:"type"`
+	// sorted set created as geo key, see geoKeysKey
+	Geo bool `json:"geo"`
+	// only set for strings
+	Value *string `json:"value"`
+	// -1 for keys without expiry
+	TTLMs int64 `json:"ttl_ms"`
+	// nil if OBJECT or MEMORY USAGE are not available
+	Object      *ObjectInfo `json:"object"`
+	MemoryUsage *int64      `json:"memory_usage"`
+}
+
+type KeyDetailViewModel struct {
+	KeyDetails
+	Display KeyValue
+}
+
+var errKeyNotFound = errors.New("key not found")
+
+// fetch the details of a key, errKeyNotFound if it does not exist
+func fetchKeyDetails(ctx context.Context, client ValkeyClient, key string) (KeyDetails, error) {
+	details := KeyDetails{Key: key}
+
+	resps := client.DoMulti(ctx,
+		client.B().Type().Key(key).Build(),
+		client.B().Pttl().Key(key).Build(),
+		client.B().MemoryUsage().Key(key).Build(),
+	)
+	var err error
+	if details.Type, err = resps[0].ToString(); err != nil {
+		return details, err
+	}
+	if details.Type == "none" {
+		return details, errKeyNotFound
+	}
+	if details.TTLMs, err = resps[1].AsInt64(); err != nil {
+		return details, err
+	}
+	if usage, err := resps[2].AsInt64(); err == nil {
+		details.MemoryUsage = &usage
+	} else {
+		log.Printf("Failed to fetch memory usage of key %v, err = %v\n", key, valkeyErr(err))
+	}
+
+	if info, err := fetchObjectInfo(ctx, client, key); err == nil {
+		details.Object = &info
+	} else {
+		log.Printf("Failed to fetch object info for key %v, err = %v\n", key, valkeyErr(err))
+	}
+
+	if details.Type == "zset" {
+		details.Geo, err = client.Do(ctx, client.B().Sismember().Key(geoKeysKey()).Member(key).Build()).AsBool()
+		if err != nil {
+			log.Printf("Failed to check geo keys for key %v, err = %v\n", key, valkeyErr(err))
+		}
+	}
+
+	if details.Type == "string" {
+		value, err := client.Do(ctx, client.B().Get().Key(key).Build()).ToString()
+		if valkey.IsValkeyNil(err) {
+			// expired in the meantime
+			return details, errKeyNotFound
+		}
+		if err != nil {
+			return details, err
+		}
+		details.Value = &value
+	}
+	return details, nil
+}
+
+// detail page of a key, JSON with "Accept: application/json"
+func renderKeyDetails(w http.ResponseWriter, r *http.Request) {
+	// PathValue is percent-decoded, so keys with slashes arrive as %2F
+	key := r.PathValue("key")
+	asJSON := strings.Contains(r.Header.Get("Accept"), "application/json")
+	fail := func(status int, message string) {
+		if asJSON {
+			writeJSONError(w, status, message)
+		} else {
+			renderError(w, r, status, message)
+		}
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		fail(http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	details, err := fetchKeyDetails(ctx, client, key)
+	if errors.Is(err, errKeyNotFound) {
+		fail(http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch details of key %v, err = %v\n", key, valkeyErr(err))
+		fail(http.StatusBadGateway, fmt.Sprintf("failed to fetch details of key %v", key))
+		return
+	}
+	recordRecentKey(ctx, client, key)
+
+	if asJSON {
+		writeJSON(w, http.StatusOK, details)
+		return
+	}
+
+	viewModel := KeyDetailViewModel{KeyDetails: details}
+	if details.Value != nil {
+		viewModel.Display = displayKeyValue(key, *details.Value, r.URL.Query().Get("raw") == "true")
+	}
+	renderTemplate(w, "detail", "base", viewModel)
+}
+
+// delete a key
+func deleteKeyValue(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	deleted, err := replyInt64(client.Do(ctx, deleteCommand(client, key)))
+	if err != nil {
+		log.Printf("Failed to delete key %v, err = %v\n", key, valkeyErr(err))
+		if writeMovedError(w, err) {
+			return
+		}
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to delete key %v", key))
+		return
+	}
+	if deleted == 0 {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return
+	}
+
+	audit(ctx, client, r, "delete", key, nil)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deleted": true})
+}
+
+// rename a key, never replaces an existing key
+func renameKeyValue(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	var body struct {
+		NewKey string `json:"new_key"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if len(body.NewKey) < 1 {
+		writeJSONError(w, http.StatusBadRequest, "new_key must not be empty")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	// RENAMENX fails with an error for a missing source
+	exists, err := client.Do(ctx, client.B().Exists().Key(key).Build()).AsInt64()
+	if err != nil {
+		log.Printf("Failed to check key %v, err = %v\n", key, valkeyErr(err))
+		if writeMovedError(w, err) {
+			return
+		}
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to check key %v", key))
+		return
+	}
+	if exists == 0 {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return
+	}
+
+	renamed, err := replyBool(client.Do(ctx, client.B().Renamenx().Key(key).Newkey(body.NewKey).Build()))
+	if err != nil {
+		log.Printf("Failed to rename key %v to %v, err = %v\n", key, body.NewKey, valkeyErr(err))
+		if writeMovedError(w, err) {
+			return
+		}
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to rename key %v", key))
+		return
+	}
+	if !renamed {
+		writeJSONError(w, http.StatusConflict, fmt.Sprintf("key %v already exists", body.NewKey))
+		return
+	}
+
+	audit(ctx, client, r, "rename", key, map[string]interface{}{"new_key": body.NewKey})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"renamed": true})
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// diff operations
+const (
+	diffEqual  = "equal"
+	diffInsert = "insert"
+	diffDelete = "delete"
+)
+
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type CompareViewModel struct {
+	A     string
+	B     string
+	Diff  []DiffLine
+	Error string
+}
+
+// limits of the compared values, the diff takes O((n+m)^2) time and memory in the worst case
+const (
+	maxDiffBytes = 1 << 20
+	maxDiffLines = 1000
+)
+
+// reject values too large for diffLines
+func checkDiffLimits(a, b string) error {
+	if len(a) > maxDiffBytes || len(b) > maxDiffBytes {
+		return fmt.Errorf("values larger than %v bytes cannot be compared", maxDiffBytes)
+	}
+	if strings.Count(a, "\n") >= maxDiffLines || strings.Count(b, "\n") >= maxDiffLines {
+		return fmt.Errorf("values with more than %v lines cannot be compared", maxDiffLines)
+	}
+	return nil
+}
+
+// line based diff of a and b using the Myers algorithm, see checkDiffLimits
+func diffLines(a, b string) []DiffLine {
+	linesA := strings.Split(a, "\n")
+	linesB := strings.Split(b, "\n")
+	n, m := len(linesA), len(linesB)
+	total := n + m
+	// one spare diagonal on both sides, the first step reads diagonal 1
+	offset := total + 1
+	v := make([]int, 2*offset+1)
+
+	// remember the furthest reaching paths of every edit distance for the backtracking
+	// step d reads the diagonals -d-1 to d+1 only, trace[d][d+1+k] is diagonal k
+	trace := make([][]int, 0)
+	for d := 0; d <= total; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && linesA[x] == linesB[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrackDiff(trace, linesA, linesB)
+			}
+		}
+	}
+	return nil
+}
+
+// walk the trace backwards from the end of both inputs and collect the edit script
+func backtrackDiff(trace [][]int, linesA, linesB []string) []DiffLine {
+	x, y := len(linesA), len(linesB)
+	reversed := make([]DiffLine, 0, x+y)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[d+k] < v[d+k+2]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[d+1+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, DiffLine{Op: diffEqual, Text: linesA[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, DiffLine{Op: diffInsert, Text: linesB[y-1]})
+			} else {
+				reversed = append(reversed, DiffLine{Op: diffDelete, Text: linesA[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	diff := make([]DiffLine, 0, len(reversed))
+	for i := len(reversed) - 1; i >= 0; i-- {
+		diff = append(diff, reversed[i])
+	}
+	return diff
+}
+
+// fetch the values of the keys a and b
+func fetchComparedValues(ctx context.Context, r *http.Request, keyA, keyB string) (string, string, int, error) {
+	if len(keyA) < 1 || len(keyB) < 1 {
+		return "", "", http.StatusBadRequest, fmt.Errorf("query parameters a and b are required")
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		return "", "", http.StatusServiceUnavailable, fmt.Errorf("failed to connect to Valkey")
+	}
+	defer client.Close()
+
+	resps := client.DoMulti(ctx,
+		client.B().Get().Key(keyA).Build(),
+		client.B().Get().Key(keyB).Build(),
+	)
+	values := make([]string, 0, len(resps))
+	for i, key := range []string{keyA, keyB} {
+		value, err := resps[i].ToString()
+		if valkey.IsValkeyNil(err) {
+			return "", "", http.StatusNotFound, fmt.Errorf("key %v not found", key)
+		}
+		if err != nil {
+			log.Printf("Failed to fetch value for key %v, err = %v\n", key, valkeyErr(err))
+			return "", "", http.StatusBadGateway, fmt.Errorf("failed to fetch value for key %v", key)
+		}
+		values = append(values, value)
+	}
+	if err := checkDiffLimits(values[0], values[1]); err != nil {
+		return "", "", http.StatusUnprocessableEntity, err
+	}
+
+	return values[0], values[1], http.StatusOK, nil
+}
+
+func renderCompare(w http.ResponseWriter, r *http.Request) {
+	viewModel := CompareViewModel{
+		A: r.URL.Query().Get("a"),
+		B: r.URL.Query().Get("b"),
+	}
+
+	// render the empty form on first visit
+	if len(viewModel.A) > 0 || len(viewModel.B) > 0 {
+		ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+		defer cancel()
+
+		valueA, valueB, _, err := fetchComparedValues(ctx, r, viewModel.A, viewModel.B)
+		if err != nil {
+			viewModel.Error = err.Error()
+		} else {
+			viewModel.Diff = diffLines(valueA, valueB)
+		}
+	}
+
+	renderTemplate(w, "compare", "base", viewModel)
+}
+
+func compareKeyValues(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	valueA, valueB, status, err := fetchComparedValues(ctx, r, r.URL.Query().Get("a"), r.URL.Query().Get("b"))
+	if err != nil {
+		writeJSONError(w, status, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"a":    valueA,
+		"b":    valueB,
+		"diff": diffLines(valueA, valueB),
+	})
+}
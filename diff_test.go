@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []DiffLine
+	}{
+		{
+			name: "equal",
+			a:    "a\nb",
+			b:    "a\nb",
+			want: []DiffLine{{Op: diffEqual, Text: "a"}, {Op: diffEqual, Text: "b"}},
+		},
+		{
+			name: "changed line",
+			a:    "a\nb\nc",
+			b:    "a\nx\nc",
+			want: []DiffLine{
+				{Op: diffEqual, Text: "a"},
+				{Op: diffDelete, Text: "b"},
+				{Op: diffInsert, Text: "x"},
+				{Op: diffEqual, Text: "c"},
+			},
+		},
+		{
+			name: "appended line",
+			a:    "a",
+			b:    "a\nb",
+			want: []DiffLine{{Op: diffEqual, Text: "a"}, {Op: diffInsert, Text: "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffLines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDiffLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		wantErr bool
+	}{
+		{name: "small values", a: "a\nb", b: "c"},
+		{name: "too many lines", a: strings.Repeat("a\n", maxDiffLines), b: "c", wantErr: true},
+		{name: "too large", a: "a", b: strings.Repeat("b", maxDiffBytes+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkDiffLimits(tt.a, tt.b); (err != nil) != tt.wantErr {
+				t.Errorf("checkDiffLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
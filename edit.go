@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+type EditViewModel struct {
+	Key   string
+	Value string
+	// size of the stored value in bytes
+	Size int
+}
+
+// editor for multi-line values, the form submits to the create handler which replaces the value
+func renderEditor(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		renderError(w, r, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	value, err := client.Do(ctx, client.B().Get().Key(key).Build()).ToString()
+	if valkey.IsValkeyNil(err) {
+		renderError(w, r, http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return
+	}
+	if err != nil && strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		renderError(w, r, http.StatusBadRequest, fmt.Sprintf("key %v is not a string", key))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch value for key %v, err = %v\n", key, valkeyErr(err))
+		renderError(w, r, http.StatusBadGateway, fmt.Sprintf("failed to fetch value for key %v", key))
+		return
+	}
+
+	renderTemplate(w, "edit", "base", EditViewModel{Key: key, Value: value, Size: len(value)})
+}
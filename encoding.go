@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// encodings that use more memory than the compact one of their type
+// and the setting up to which the server keeps the compact encoding
+var encodingThresholds = []struct {
+	Type     string
+	Encoding string
+	Compact  string
+	Setting  string
+}{
+	{"hash", "hashtable", "listpack", "hash-max-listpack-entries"},
+	{"set", "hashtable", "listpack", "set-max-listpack-entries"},
+	{"zset", "skiplist", "listpack", "zset-max-listpack-entries"},
+	{"list", "quicklist", "listpack", "list-max-listpack-size"},
+}
+
+// current values of the encoding settings, missing if CONFIG is disabled or the server predates them
+func fetchEncodingSettings(ctx context.Context, client ValkeyClient) map[string]string {
+	cmds := make(valkey.Commands, len(encodingThresholds))
+	for i, threshold := range encodingThresholds {
+		cmds[i] = client.B().ConfigGet().Parameter(threshold.Setting).Build()
+	}
+	settings := make(map[string]string)
+	for i, resp := range client.DoMulti(ctx, cmds...) {
+		config, err := resp.AsStrMap()
+		if err != nil {
+			log.Printf("Failed to fetch %v, err = %v\n", encodingThresholds[i].Setting, valkeyErr(err))
+			continue
+		}
+		if value, ok := config[encodingThresholds[i].Setting]; ok {
+			settings[encodingThresholds[i].Setting] = value
+		}
+	}
+	return settings
+}
+
+// histogram of the OBJECT ENCODING of all keys with tuning recommendations
+// the report is incomplete if the SCAN takes longer than countScanTimeout
+func encodingReport(w http.ResponseWriter, r *http.Request) {
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), countScanDeadline())
+	defer cancel()
+
+	encodings := make(map[string]int64)
+	// per type, e.g. "hash" -> "hashtable" -> 300
+	typeEncodings := make(map[string]map[string]int64)
+	stopAt := time.Now().Add(countScanTimeout)
+	complete := true
+	var cursor uint64
+	for {
+		entry, err := client.Do(ctx, client.B().Scan().Cursor(cursor).Count(scanCount).Build()).AsScanEntry()
+		if err != nil {
+			log.Printf("Failed to scan keys, err = %v\n", valkeyErr(err))
+			writeJSONError(w, http.StatusBadGateway, "failed to scan keys")
+			return
+		}
+		keys := make([]string, 0, len(entry.Elements))
+		for _, key := range entry.Elements {
+			if !isInternalKey(key) {
+				keys = append(keys, key)
+			}
+		}
+		cmds := make(valkey.Commands, 0, 2*len(keys))
+		for _, key := range keys {
+			cmds = append(cmds, client.B().Type().Key(key).Build(), client.B().ObjectEncoding().Key(key).Build())
+		}
+		resps := client.DoMulti(ctx, cmds...)
+		for i, key := range keys {
+			keyType, err := resps[2*i].ToString()
+			if err != nil || keyType == "none" {
+				// expired since the scan
+				continue
+			}
+			encoding, err := resps[2*i+1].ToString()
+			if err != nil {
+				log.Printf("Failed to fetch encoding of key %v, err = %v\n", key, valkeyErr(err))
+				continue
+			}
+			encodings[encoding]++
+			if typeEncodings[keyType] == nil {
+				typeEncodings[keyType] = make(map[string]int64)
+			}
+			typeEncodings[keyType][encoding]++
+		}
+
+		cursor = entry.Cursor
+		if cursor == 0 {
+			break
+		}
+		if time.Now().After(stopAt) {
+			complete = false
+			break
+		}
+	}
+
+	settings := fetchEncodingSettings(ctx, client)
+	recommendations := make([]string, 0)
+	for _, threshold := range encodingThresholds {
+		count := typeEncodings[threshold.Type][threshold.Encoding]
+		if count < 1 {
+			continue
+		}
+		current := ""
+		if value, ok := settings[threshold.Setting]; ok {
+			current = fmt.Sprintf(" (currently %v)", value)
+		}
+		recommendations = append(recommendations, fmt.Sprintf("%v %v keys use %v; consider tuning %v%v to use %v.",
+			count, threshold.Type, threshold.Encoding, threshold.Setting, current, threshold.Compact))
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"encodings":       encodings,
+		"by_type":         typeEncodings,
+		"settings":        settings,
+		"recommendations": recommendations,
+		"complete":        complete,
+	})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// configuration variables shown by /admin/env as they are
+// LOG_LEVEL and VALKEY_DB are listed for the support checklists, the app reads neither and they are usually empty
+var plainEnvVars = []string{
+	"PORT", "HTTP_ADDR", "LOG_LEVEL", "VALKEY_ENV_PREFIX", "VALKEY_DB", "PID_FILE",
+	"HTTP_READ_TIMEOUT", "HTTP_WRITE_TIMEOUT", "HTTP_IDLE_TIMEOUT", "HTTP_READ_HEADER_TIMEOUT",
+	"VALKEY_CMD_TIMEOUT", "VALKEY_CONN_MAX_LIFETIME", "VALKEY_POOL_SIZE", "VALKEY_MAX_IDLE_CONNS", "VALKEY_POOL_TIMEOUT",
+	"VALKEY_MAX_RETRIES", "VALKEY_MIN_RETRY_BACKOFF", "VALKEY_MAX_RETRY_BACKOFF",
+	"VALKEY_RESP_VERSION", "VALKEY_READ_REPLICA_ONLY", "VALKEY_DISABLE_MOVED_REDIRECT", "VALKEY_USE_UNLINK",
+	"VALKEY_SERVICE_NAME", "VALKEY_SERVICE_INDEX",
+	"VALKEY_INTERNAL_PREFIX", "VALKEY_KEYSPACE_EVENTS", "VALKEY_DRY_RUN", "VALKEY_CHAOS_RATE",
+	"VALKEY_HISTORY_DEPTH", "VALKEY_VALUE_HISTORY", "VALKEY_SCAN_COUNT", "VALKEY_SEARCH_ENABLED", "MAX_COUNT_SCAN_MS",
+	"STATS_REFRESH_INTERVAL", "AUTO_REFRESH_SECONDS",
+	"TRUST_PROXY", "RATE_LIMIT_RPS", "RATE_LIMIT_BURST", "CORS_ALLOWED_ORIGINS", "APP_TEMPLATE_DIR",
+	"TLS_PORT", "TLS_CERT_FILE", "TLS_KEY_FILE", "TLS_AUTO_CERT_DOMAIN", "TLS_AUTO_CERT_CACHE_DIR",
+	"ENABLE_DEBUG_SLEEP", "ENABLE_DEBUG_COMMANDS",
+}
+
+// configuration variables holding secrets, only shown as set or not set
+var secretEnvVars = []string{"ADMIN_TOKEN", "COOKIE_SECRET"}
+
+// running configuration for support engineers, secrets are redacted
+func renderEnv(w http.ResponseWriter, r *http.Request) {
+	env := make(map[string]interface{})
+	for _, name := range plainEnvVars {
+		env[name] = os.Getenv(name)
+	}
+	for _, name := range secretEnvVars {
+		env[name] = redacted(os.Getenv(name))
+	}
+
+	// the local credentials and the Sentinel setup honor VALKEY_ENV_PREFIX
+	prefix := envPrefix()
+	for _, name := range []string{"HOST", "PORT", "USERNAME", "SENTINEL_MASTER", "SENTINEL_USERNAME"} {
+		env[prefix+"_"+name] = os.Getenv(prefix + "_" + name)
+	}
+	for _, name := range []string{"PASSWORD", "SENTINEL_PASSWORD"} {
+		env[prefix+"_"+name] = redacted(os.Getenv(prefix + "_" + name))
+	}
+	// the length is enough to tell a truncated certificate
+	if caCert := os.Getenv(prefix + "_CA_CERT"); len(caCert) > 0 {
+		env[prefix+"_CA_CERT"] = fmt.Sprintf("<%v chars>", len(caCert))
+	} else {
+		env[prefix+"_CA_CERT"] = ""
+	}
+	// the service bindings and the cluster list contain the credentials
+	env["VCAP_SERVICES"] = len(os.Getenv("VCAP_SERVICES")) > 0
+	env["VALKEY_CLUSTERS"] = len(os.Getenv("VALKEY_CLUSTERS")) > 0
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"env": env, "features": features})
+}
+
+func redacted(value string) string {
+	if len(value) < 1 {
+		return ""
+	}
+	return "***"
+}
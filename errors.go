@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type ErrorViewModel struct {
+	Code    int
+	Message string
+	// shown on 5xx pages for support requests
+	RequestID string
+	// similarly named keys on 404 pages
+	Suggestions []string
+}
+
+type requestIDKey struct{}
+
+// id of the request for the logs and the error pages, see withRequestID
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// take the X-Request-ID of the router or generate one, it is returned in the response headers
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if len(id) < 1 || len(id) > 64 {
+			raw := make([]byte, 8)
+			rand.Read(raw)
+			id = hex.EncodeToString(raw)
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// error page of the HTML handlers
+// the plain API endpoints, e.g. the raw value download, keep answering with text
+func renderError(w http.ResponseWriter, r *http.Request, code int, message string) {
+	renderErrorPage(w, r, ErrorViewModel{Code: code, Message: message})
+}
+
+func renderErrorPage(w http.ResponseWriter, r *http.Request, viewModel ErrorViewModel) {
+	code, message := viewModel.Code, viewModel.Message
+	if code >= http.StatusInternalServerError {
+		log.Printf("Responding %v to request %v: %v\n", code, requestID(r), message)
+	}
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		http.Error(w, message, code)
+		return
+	}
+
+	if code >= http.StatusInternalServerError {
+		viewModel.RequestID = requestID(r)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	if err := templates["error"].ExecuteTemplate(w, "base", viewModel); err != nil {
+		log.Printf("Failed to render error page: %v", err)
+	}
+}
+
+// number of suggestions on 404 pages
+const suggestionLimit = 5
+
+// escape the glob characters of a MATCH pattern
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// keys containing the key segment of a /key-values/ path, e.g. /key-values/usr/bogus suggests "user:usr"
+// only a single SCAN page is searched, large keyspaces may miss matches
+func suggestKeys(ctx context.Context, client ValkeyClient, path string) ([]string, error) {
+	segments := strings.FieldsFunc(path, func(c rune) bool { return c == '/' })
+	if len(segments) < 2 || segments[0] != "key-values" {
+		return nil, nil
+	}
+	pattern := "*" + globEscaper.Replace(segments[1]) + "*"
+
+	entry, err := client.Do(ctx, client.B().Scan().Cursor(0).Match(pattern).Count(scanCount).Build()).AsScanEntry()
+	if err != nil {
+		return nil, err
+	}
+	suggestions := []string{}
+	for _, key := range entry.Elements {
+		if isInternalKey(key) {
+			continue
+		}
+		suggestions = append(suggestions, key)
+		if len(suggestions) >= suggestionLimit {
+			break
+		}
+	}
+	return suggestions, nil
+}
+
+// unknown routes, pages below /key-values/ suggest keys named like the key segment
+func notFound(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		writeJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+	viewModel := ErrorViewModel{Code: http.StatusNotFound, Message: fmt.Sprintf("Page %v not found", r.URL.Path)}
+	if !strings.HasPrefix(r.URL.Path, "/key-values/") {
+		renderErrorPage(w, r, viewModel)
+		return
+	}
+
+	// suggestions are optional, the page is rendered without them on failures
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+	} else {
+		defer client.Close()
+		ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+		defer cancel()
+		viewModel.Suggestions, err = suggestKeys(ctx, client, r.URL.Path)
+		if err != nil {
+			log.Printf("Failed to search keys like %v, err = %v\n", r.URL.Path, valkeyErr(err))
+		}
+	}
+	renderErrorPage(w, r, viewModel)
+}
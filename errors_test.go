@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNotFoundSuggestions(t *testing.T) {
+	loadTemplates()
+
+	tests := []struct {
+		name     string
+		path     string
+		connects bool
+		contains string
+	}{
+		{name: "key path", path: "/key-values/usr/bogus", connects: true, contains: "user:usr"},
+		{name: "other path", path: "/usr", connects: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewMockValkeyClient()
+			client.store["user:usr"] = "alice"
+			connected := false
+			previous := newClient
+			newClient = func(r *http.Request) (ValkeyClient, error) {
+				connected = true
+				return client, nil
+			}
+			t.Cleanup(func() { newClient = previous })
+
+			w := httptest.NewRecorder()
+			notFound(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != http.StatusNotFound {
+				t.Errorf("status = %v, want %v", w.Code, http.StatusNotFound)
+			}
+			if connected != tt.connects {
+				t.Errorf("connected = %v, want %v", connected, tt.connects)
+			}
+			if len(tt.contains) > 0 && !strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("body does not contain %q", tt.contains)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// number of keyspace events kept for the pollers
+const eventsListSize = 1000
+
+// longest wait of a poll without new events
+const eventPollWait = 30 * time.Second
+
+type KeyspaceEvent struct {
+	ID    int64  `json:"id"`
+	Key   string `json:"key"`
+	Event string `json:"event"`
+	Time  string `json:"time"`
+}
+
+// keep the keyspace notifications of the selected database of the cluster in __a9s__:events
+// the notifications need the K flag in VALKEY_KEYSPACE_EVENTS, e.g. "K$gx"
+// every instance of the app subscribes, only the holder of the recorder lease records the events
+func subscribeKeyspaceEvents(cluster *ClusterConfig) {
+	lease := &eventsLease{cluster: cluster, id: newLeaseID()}
+	go lease.hold()
+
+	for {
+		client, err := newClusterClient(cluster)
+		if err != nil {
+			log.Printf("Failed to create connection: %v", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		// the messages arrive on the reading goroutine of the connection, which must not wait for replies
+		pending := make(chan KeyspaceEvent, eventsListSize)
+		go func() {
+			for event := range pending {
+				recordEvent(client, event.Key, event.Event)
+			}
+		}()
+
+		prefix := fmt.Sprintf("__keyspace@%v__:", valkeyDB)
+		err = client.Receive(context.Background(), client.B().Psubscribe().Pattern(prefix+"*").Build(), func(msg valkey.PubSubMessage) {
+			key := strings.TrimPrefix(msg.Channel, prefix)
+			// recording an event writes internal keys, which would notify again
+			if isInternalKey(key) || !lease.held.Load() {
+				return
+			}
+			select {
+			case pending <- KeyspaceEvent{Key: key, Event: msg.Message}:
+			default:
+				log.Printf("Dropping event %v for key %v, the recording falls behind\n", msg.Message, key)
+			}
+		})
+		close(pending)
+		client.Close()
+		log.Printf("Keyspace event subscription ended, err = %v\n", valkeyErr(err))
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// lifetime of the recorder lease, renewed every third of it
+const eventsLeaseTTL = 15 * time.Second
+
+// takes the lease with SET NX, or extends it if this instance holds it already
+var eventsLeaseScript = `if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return 1
+end
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+return 0`
+
+// the events would be stored once per instance of the app otherwise
+// another instance takes over once the lease of a stopped one expires
+type eventsLease struct {
+	cluster *ClusterConfig
+	id      string
+	held    atomic.Bool
+}
+
+func newLeaseID() string {
+	raw := make([]byte, 8)
+	rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
+
+func (l *eventsLease) hold() {
+	for {
+		l.held.Store(l.renew())
+		time.Sleep(eventsLeaseTTL / 3)
+	}
+}
+
+func (l *eventsLease) renew() bool {
+	client, err := newClusterClient(l.cluster)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		return false
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(context.Background(), valkeyCmdTimeout)
+	defer cancel()
+
+	script := client.B().Eval().Script(eventsLeaseScript).Numkeys(1).Key(internalKey("events", "recorder")).
+		Arg(l.id, strconv.FormatInt(eventsLeaseTTL.Milliseconds(), 10)).Build()
+	held, err := client.Do(ctx, script).AsBool()
+	if err != nil {
+		log.Printf("Failed to renew the event recorder lease, err = %v\n", valkeyErr(err))
+		return false
+	}
+	if held != l.held.Load() {
+		log.Printf("Event recorder lease held: %v\n", held)
+	}
+	return held
+}
+
+// pollers of a cluster waiting for the next event
+type eventWaiters struct {
+	mu   sync.Mutex
+	next chan struct{}
+}
+
+// closed once the next event of the cluster is recorded
+func (w *eventWaiters) wait() <-chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.next
+}
+
+func (w *eventWaiters) wake() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	close(w.next)
+	w.next = make(chan struct{})
+}
+
+// keyed by the cluster name, "" without VALKEY_CLUSTERS
+var (
+	eventWaitersMu        sync.Mutex
+	eventWaitersByCluster = make(map[string]*eventWaiters)
+)
+
+func clusterEventWaiters(cluster *ClusterConfig) *eventWaiters {
+	name := ""
+	if cluster != nil {
+		name = cluster.Name
+	}
+
+	eventWaitersMu.Lock()
+	defer eventWaitersMu.Unlock()
+	waiters, ok := eventWaitersByCluster[name]
+	if !ok {
+		waiters = &eventWaiters{next: make(chan struct{})}
+		eventWaitersByCluster[name] = waiters
+	}
+	return waiters
+}
+
+// a single subscription to __a9s__:event_trigger per cluster wakes all pollers of the process
+// the subscription shares the connection of the client and takes no slot of the blocking pool
+func watchEventTrigger(cluster *ClusterConfig) {
+	waiters := clusterEventWaiters(cluster)
+	for {
+		client, err := newClusterClient(cluster)
+		if err != nil {
+			log.Printf("Failed to create connection: %v", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		err = client.Receive(context.Background(), client.B().Subscribe().Channel(internalKey("event_trigger")).Build(), func(valkey.PubSubMessage) {
+			waiters.wake()
+		})
+		client.Close()
+		log.Printf("Event trigger subscription ended, err = %v\n", valkeyErr(err))
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// append an event to the capped list and wake the waiting pollers
+func recordEvent(client ValkeyClient, key string, event string) {
+	ctx, cancel := withDeadline(context.Background(), valkeyCmdTimeout)
+	defer cancel()
+
+	id, err := client.Do(ctx, client.B().Incr().Key(internalKey("events", "seq")).Build()).AsInt64()
+	if err != nil {
+		log.Printf("Failed to record event for key %v, err = %v\n", key, valkeyErr(err))
+		return
+	}
+	data, err := json.Marshal(KeyspaceEvent{ID: id, Key: key, Event: event, Time: time.Now().UTC().Format(time.RFC3339)})
+	if err != nil {
+		log.Printf("Failed to encode event: %v", err)
+		return
+	}
+
+	eventsKey := internalKey("events")
+	for _, resp := range client.DoMulti(ctx,
+		client.B().Lpush().Key(eventsKey).Element(string(data)).Build(),
+		client.B().Ltrim().Key(eventsKey).Start(0).Stop(eventsListSize-1).Build(),
+		// wakes the pollers of every instance of the app, they read the events list
+		client.B().Publish().Channel(internalKey("event_trigger")).Message(strconv.FormatInt(id, 10)).Build(),
+	) {
+		if err := resp.Error(); err != nil {
+			log.Printf("Failed to record event for key %v, err = %v\n", key, valkeyErr(err))
+			return
+		}
+	}
+}
+
+// events with an ID above since, oldest first
+func fetchEventsSince(ctx context.Context, client ValkeyClient, since int64) ([]KeyspaceEvent, error) {
+	entries, err := client.Do(ctx, client.B().Lrange().Key(internalKey("events")).Start(0).Stop(-1).Build()).AsStrSlice()
+	if err != nil {
+		return nil, err
+	}
+	events := []KeyspaceEvent{}
+	for _, entry := range entries {
+		var event KeyspaceEvent
+		if err := json.Unmarshal([]byte(entry), &event); err != nil {
+			log.Printf("Skipping invalid event %q: %v", entry, err)
+			continue
+		}
+		if event.ID > since {
+			events = append(events, event)
+		}
+	}
+	// the list is newest first
+	slices.Reverse(events)
+	return events, nil
+}
+
+// long polling for clients behind proxies without SSE support
+// waits up to 30s for events after ?since=, the client polls again with the returned last_event_id
+func pollEvents(w http.ResponseWriter, r *http.Request) {
+	var since int64
+	if sinceStr := r.URL.Query().Get("since"); len(sinceStr) > 0 {
+		var err error
+		since, err = strconv.ParseInt(sinceStr, 10, 64)
+		if err != nil || since < 0 {
+			writeJSONError(w, http.StatusBadRequest, "since must be a non-negative event id")
+			return
+		}
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), eventPollWait+valkeyCmdTimeout)
+	defer cancel()
+
+	events, err := waitForEvents(ctx, client, clusterEventWaiters(selectedCluster(r)), since)
+	if err != nil {
+		log.Printf("Failed to poll events since %v, err = %v\n", since, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to poll events")
+		return
+	}
+
+	lastID := since
+	if len(events) > 0 {
+		lastID = events[len(events)-1].ID
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"events": events, "last_event_id": lastID})
+}
+
+// events after since, waiting up to eventPollWait for the first one
+// the wake channel is taken before the events are read, an event recorded in between wakes the poll as well
+func waitForEvents(ctx context.Context, client ValkeyClient, waiters *eventWaiters, since int64) ([]KeyspaceEvent, error) {
+	wait := time.NewTimer(eventPollWait)
+	defer wait.Stop()
+	for {
+		woken := waiters.wait()
+		events, err := fetchEventsSince(ctx, client, since)
+		if err != nil || len(events) > 0 {
+			return events, err
+		}
+		select {
+		case <-woken:
+		case <-wait.C:
+			// no events within the wait
+			return events, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
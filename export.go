@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// stream all keys as a JSON array without buffering the keyspace
+// the array is written page by page while scanning, so an error after the first page
+// can no longer change the status and leaves the array unterminated
+func exportKeyValues(w http.ResponseWriter, r *http.Request) {
+	showInternal := r.URL.Query().Get("internal") == "true"
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="export.json"`)
+	w.Header().Set("Transfer-Encoding", "chunked")
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+
+	opened, first := false, true
+	var cursor uint64
+	for {
+		// every page gets its own deadline, a full export takes longer than a single request
+		ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+		entry, err := client.Do(ctx, client.B().Scan().Cursor(cursor).Count(scanCount).Build()).AsScanEntry()
+		if err != nil {
+			cancel()
+			log.Printf("Failed to scan keys for export, err = %v\n", valkeyErr(err))
+			if !opened {
+				writeJSONError(w, http.StatusBadGateway, "failed to export keys")
+			}
+			return
+		}
+
+		keys := make([]string, 0, len(entry.Elements))
+		for _, key := range entry.Elements {
+			if showInternal || !isInternalKey(key) {
+				keys = append(keys, key)
+			}
+		}
+		cmds := make(valkey.Commands, 0, 2*len(keys))
+		for _, key := range keys {
+			cmds = append(cmds, client.B().Type().Key(key).Build(), client.B().Get().Key(key).Build())
+		}
+		var resps []valkey.ValkeyResult
+		if len(cmds) > 0 {
+			resps = client.DoMulti(ctx, cmds...)
+		}
+		cancel()
+
+		if !opened {
+			w.Write([]byte("["))
+			opened = true
+		}
+		for i, key := range keys {
+			keyType, err := resps[2*i].ToString()
+			if err != nil {
+				log.Printf("Failed to fetch type of key %v, err = %v\n", key, valkeyErr(err))
+				continue
+			}
+			keyValue := KeyValue{Key: key}
+			switch keyType {
+			case "none":
+				// expired since the scan
+				continue
+			case "string":
+				if keyValue.Value, err = resps[2*i+1].ToString(); err != nil {
+					log.Printf("Failed to fetch value for key %v, err = %v\n", key, valkeyErr(err))
+					continue
+				}
+			default:
+				keyValue.Type = keyType
+			}
+			if !first {
+				w.Write([]byte(","))
+			}
+			first = false
+			// Encode appends a newline, which keeps the array valid JSON
+			encoder.Encode(keyValue)
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		cursor = entry.Cursor
+		if cursor == 0 {
+			break
+		}
+	}
+	w.Write([]byte("]\n"))
+}
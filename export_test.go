@@ -0,0 +1,25 @@
+package main
+
+import "net/http"
+
+// internals used by the integration tests of package main_test
+var (
+	InitTemplates  = initTemplates
+	RegisterRoutes = registerRoutes
+	RootHandler    = rootHandler
+	CreateKeyValue = createKeyValue
+)
+
+// every request dials a client of its own instead of sharing the one of the cluster,
+// the returned func restores the shared clients
+func DialPerRequest() (restore func()) {
+	previous := newClient
+	newClient = func(r *http.Request) (ValkeyClient, error) {
+		client, err := NewClient(selectedCluster(r))
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+	return func() { newClient = previous }
+}
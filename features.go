@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"os"
+)
+
+// beta features, disabled unless FEATURE_<NAME>=true
+type FeatureFlags struct {
+	AdminPanel bool `json:"admin_panel"`
+	Streams    bool `json:"streams"`
+	Tags       bool `json:"tags"`
+}
+
+// populated at startup, see loadFeatureFlags
+var features FeatureFlags
+
+func loadFeatureFlags() FeatureFlags {
+	return FeatureFlags{
+		AdminPanel: os.Getenv("FEATURE_ADMIN_PANEL") == "true",
+		Streams:    os.Getenv("FEATURE_STREAMS") == "true",
+		Tags:       os.Getenv("FEATURE_TAGS") == "true",
+	}
+}
+
+// answer 404 while the feature is disabled, as if the route did not exist
+func requireFeature(enabled bool, handler http.HandlerFunc) http.HandlerFunc {
+	if enabled {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}
+}
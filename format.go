@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"html/template"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// whether the value can be displayed as text
+func isPrintable(value string) bool {
+	if !utf8.ValidString(value) {
+		return false
+	}
+	for _, r := range value {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// value formats, see detectFormat
+const (
+	formatJSON   = "json"
+	formatXML    = "xml"
+	formatBase64 = "base64"
+	formatUTF8   = "utf8"
+	formatBinary = "binary"
+	// HyperLogLog sketches are strings with a "HYLL" header
+	formatHyperLogLog = "HyperLogLog"
+)
+
+// guess the format of a value
+// only objects and arrays count as JSON, otherwise every number would be a JSON document
+// short strings are never treated as base64 since plain words like "test" decode fine
+func detectFormat(v []byte) string {
+	if bytes.HasPrefix(v, []byte("HYLL")) {
+		return formatHyperLogLog
+	}
+	trimmed := bytes.TrimSpace(v)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return formatJSON
+	}
+	if len(trimmed) > 0 && trimmed[0] == '<' && xml.Unmarshal(trimmed, new(struct{})) == nil {
+		return formatXML
+	}
+	if len(trimmed) >= 16 && len(trimmed)%4 == 0 {
+		_, err := base64.StdEncoding.DecodeString(string(trimmed))
+		if err == nil {
+			return formatBase64
+		}
+	}
+	if isPrintable(string(v)) {
+		return formatUTF8
+	}
+	return formatBinary
+}
+
+// prepare a key value pair for the html views
+// binary values and HyperLogLog sketches are shown base64 encoded, the hex dump is kept for the toggle
+// JSON and XML documents are pretty printed and base64 values decoded unless raw is set
+func displayKeyValue(key string, value string, raw bool) KeyValue {
+	keyValue := KeyValue{Key: key, Value: value, Format: detectFormat([]byte(value))}
+	if keyValue.Format == formatBinary || keyValue.Format == formatHyperLogLog {
+		keyValue.Value = base64.StdEncoding.EncodeToString([]byte(value))
+		keyValue.Hex = hex.Dump([]byte(value))
+		return keyValue
+	}
+	if raw {
+		return keyValue
+	}
+
+	switch keyValue.Format {
+	case formatJSON:
+		keyValue.Pretty = prettyJSON(value)
+	case formatXML:
+		keyValue.Pretty = highlightXML(strings.TrimSpace(value))
+	case formatBase64:
+		decoded, _ := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if isPrintable(string(decoded)) {
+			keyValue.Decoded = string(decoded)
+		} else {
+			keyValue.Decoded = hex.Dump(decoded)
+		}
+	}
+	return keyValue
+}
+
+// indent and highlight a JSON document
+func prettyJSON(value string) template.HTML {
+	trimmed := strings.TrimSpace(value)
+
+	var indented bytes.Buffer
+	err := json.Indent(&indented, []byte(trimmed), "", "  ")
+	if err != nil {
+		return ""
+	}
+	return highlightJSON(indented.String())
+}
+
+// wrap the tokens of valid JSON in spans, the colors are defined in style.css
+func highlightJSON(value string) template.HTML {
+	var out strings.Builder
+	span := func(class string, token string) {
+		out.WriteString(`<span class="json-` + class + `">`)
+		out.WriteString(template.HTMLEscapeString(token))
+		out.WriteString(`</span>`)
+	}
+
+	for i := 0; i < len(value); {
+		c := value[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(value) && value[end] != '"' {
+				if value[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end++
+			// a string directly followed by a colon is an object key
+			rest := strings.TrimLeft(value[end:], " ")
+			if strings.HasPrefix(rest, ":") {
+				span("key", value[i:end])
+			} else {
+				span("string", value[i:end])
+			}
+			i = end
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(value) && strings.IndexByte("0123456789+-.eE", value[end]) >= 0 {
+				end++
+			}
+			span("number", value[i:end])
+			i = end
+		case strings.HasPrefix(value[i:], "true"):
+			span("boolean", "true")
+			i += len("true")
+		case strings.HasPrefix(value[i:], "false"):
+			span("boolean", "false")
+			i += len("false")
+		case strings.HasPrefix(value[i:], "null"):
+			span("null", "null")
+			i += len("null")
+		default:
+			out.WriteString(template.HTMLEscapeString(string(c)))
+			i++
+		}
+	}
+	return template.HTML(out.String())
+}
+
+// wrap the tags of an XML document in spans, the colors are defined in style.css
+func highlightXML(value string) template.HTML {
+	var out strings.Builder
+	for len(value) > 0 {
+		start := strings.IndexByte(value, '<')
+		if start < 0 {
+			out.WriteString(template.HTMLEscapeString(value))
+			break
+		}
+		end := strings.IndexByte(value[start:], '>')
+		if end < 0 {
+			out.WriteString(template.HTMLEscapeString(value))
+			break
+		}
+		end += start + 1
+		out.WriteString(template.HTMLEscapeString(value[:start]))
+		out.WriteString(`<span class="xml-tag">`)
+		out.WriteString(template.HTMLEscapeString(value[start:end]))
+		out.WriteString(`</span>`)
+		value = value[end:]
+	}
+	return template.HTML(out.String())
+}
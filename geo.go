@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// geo keys are sorted sets, the app remembers which ones it created as geo keys
+const typeGeo = "geo"
+
+// radius covering the whole earth, used to list all members of a geo key
+const earthRadiusKm = 20100
+
+func geoKeysKey() string {
+	return internalKey("geo")
+}
+
+// set of the keys created as geo keys
+func fetchGeoKeys(ctx context.Context, client ValkeyClient) (map[string]bool, error) {
+	keys, err := client.Do(ctx, client.B().Smembers().Key(geoKeysKey()).Build()).AsStrSlice()
+	if err != nil {
+		return nil, err
+	}
+	geoKeys := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		geoKeys[key] = true
+	}
+	return geoKeys, nil
+}
+
+// add a member to a geo key from the fields of the new key form
+func addGeoMember(ctx context.Context, client ValkeyClient, key string, longitudeStr string, latitudeStr string, member string) error {
+	longitude, err := strconv.ParseFloat(longitudeStr, 64)
+	if err != nil {
+		return fmt.Errorf("invalid longitude %v", longitudeStr)
+	}
+	latitude, err := strconv.ParseFloat(latitudeStr, 64)
+	if err != nil {
+		return fmt.Errorf("invalid latitude %v", latitudeStr)
+	}
+
+	for _, resp := range client.DoMulti(ctx,
+		client.B().Geoadd().Key(key).LongitudeLatitudeMember().LongitudeLatitudeMember(longitude, latitude, member).Build(),
+		client.B().Sadd().Key(geoKeysKey()).Member(key).Build(),
+	) {
+		if err := resp.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type GeoViewModel struct {
+	Key string
+	// center of the listing, the first member unless ?from= is given
+	From    string
+	Members []valkey.GeoLocation
+	// GEODIST of ?a= and ?b=
+	A        string
+	B        string
+	Distance string
+	Error    string
+}
+
+// members of a geo key with their coordinates and distance to a center member
+func renderGeo(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	viewModel := GeoViewModel{
+		Key:  key,
+		From: r.URL.Query().Get("from"),
+		A:    r.URL.Query().Get("a"),
+		B:    r.URL.Query().Get("b"),
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		renderError(w, r, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	if len(viewModel.From) < 1 {
+		first, err := client.Do(ctx, client.B().Zrange().Key(key).Min("0").Max("0").Build()).AsStrSlice()
+		if err != nil {
+			log.Printf("Failed to fetch members of key %v, err = %v\n", key, valkeyErr(err))
+			renderError(w, r, http.StatusBadGateway, fmt.Sprintf("failed to fetch members of key %v", key))
+			return
+		}
+		if len(first) < 1 {
+			renderError(w, r, http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+			return
+		}
+		viewModel.From = first[0]
+	}
+
+	viewModel.Members, err = client.Do(ctx, client.B().Geosearch().Key(key).Frommember(viewModel.From).
+		Byradius(earthRadiusKm).Km().Asc().Withcoord().Withdist().Build()).AsGeosearch()
+	if err != nil {
+		log.Printf("Failed to search members of key %v, err = %v\n", key, valkeyErr(err))
+		renderError(w, r, http.StatusBadGateway, fmt.Sprintf("failed to search members of key %v", key))
+		return
+	}
+
+	if len(viewModel.A) > 0 && len(viewModel.B) > 0 {
+		distance, err := client.Do(ctx, client.B().Geodist().Key(key).Member1(viewModel.A).Member2(viewModel.B).Km().Build()).AsFloat64()
+		switch {
+		case valkey.IsValkeyNil(err):
+			viewModel.Error = fmt.Sprintf("%v or %v is no member of %v", viewModel.A, viewModel.B, key)
+		case err != nil:
+			log.Printf("Failed to fetch distance in key %v, err = %v\n", key, valkeyErr(err))
+			viewModel.Error = "failed to fetch the distance"
+		default:
+			viewModel.Distance = fmt.Sprintf("%.3f km", distance)
+		}
+	}
+
+	renderTemplate(w, "geo", "base", viewModel)
+}
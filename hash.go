@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+type HashField struct {
+	Field string
+	Value string
+}
+
+type HashViewModel struct {
+	Key    string
+	Length int64
+	Fields []HashField
+	// message of a failed form submission, see redirectToKeyView
+	Error string
+}
+
+// back to a type specific view like /key-values/{key}/hash, with an error message if set
+func redirectToKeyView(w http.ResponseWriter, r *http.Request, key string, view string, message string) {
+	target := "/key-values/" + url.PathEscape(key) + "/" + view
+	if len(message) > 0 {
+		target += "?error=" + url.QueryEscape(message)
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// fields of a hash with inline edit and delete forms
+func renderHash(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		renderError(w, r, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	resps := client.DoMulti(ctx,
+		client.B().Hlen().Key(key).Build(),
+		client.B().Hgetall().Key(key).Build(),
+	)
+	viewModel := HashViewModel{Key: key, Error: r.URL.Query().Get("error")}
+	if viewModel.Length, err = resps[0].AsInt64(); err != nil {
+		log.Printf("Failed to fetch length of hash %v, err = %v\n", key, valkeyErr(err))
+		renderError(w, r, http.StatusBadGateway, fmt.Sprintf("failed to fetch hash %v", key))
+		return
+	}
+	fields, err := resps[1].AsStrMap()
+	if err != nil {
+		log.Printf("Failed to fetch fields of hash %v, err = %v\n", key, valkeyErr(err))
+		renderError(w, r, http.StatusBadGateway, fmt.Sprintf("failed to fetch hash %v", key))
+		return
+	}
+	for field, value := range fields {
+		viewModel.Fields = append(viewModel.Fields, HashField{Field: field, Value: value})
+	}
+	sort.Slice(viewModel.Fields, func(i, j int) bool {
+		return viewModel.Fields[i].Field < viewModel.Fields[j].Field
+	})
+
+	renderTemplate(w, "hash", "base", viewModel)
+}
+
+// add a field, replaces the value of an existing field
+func addHashField(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	r.ParseForm()
+	field := r.PostFormValue("field")
+	if len(field) < 1 {
+		redirectToKeyView(w, r, key, "hash", "field must not be empty")
+		return
+	}
+	value := r.PostFormValue("value")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		redirectToKeyView(w, r, key, "hash", "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	err = client.Do(ctx, client.B().Hset().Key(key).FieldValue().FieldValue(field, value).Build()).Error()
+	if err != nil {
+		log.Printf("Failed to set field %v of hash %v, err = %v\n", field, key, valkeyErr(err))
+		redirectToKeyView(w, r, key, "hash", fmt.Sprintf("failed to set field %v", field))
+		return
+	}
+	redirectToKeyView(w, r, key, "hash", "")
+}
+
+// change the value of an existing field
+func editHashField(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	field := r.PathValue("field")
+	r.ParseForm()
+	value := r.PostFormValue("value")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		redirectToKeyView(w, r, key, "hash", "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	// the field may have been deleted since the page was rendered, editing must not recreate it
+	exists, err := client.Do(ctx, client.B().Hexists().Key(key).Field(field).Build()).AsBool()
+	if err != nil {
+		log.Printf("Failed to check field %v of hash %v, err = %v\n", field, key, valkeyErr(err))
+		redirectToKeyView(w, r, key, "hash", fmt.Sprintf("failed to check field %v", field))
+		return
+	}
+	if !exists {
+		redirectToKeyView(w, r, key, "hash", fmt.Sprintf("field %v was deleted in the meantime", field))
+		return
+	}
+
+	err = client.Do(ctx, client.B().Hset().Key(key).FieldValue().FieldValue(field, value).Build()).Error()
+	if err != nil {
+		log.Printf("Failed to set field %v of hash %v, err = %v\n", field, key, valkeyErr(err))
+		redirectToKeyView(w, r, key, "hash", fmt.Sprintf("failed to set field %v", field))
+		return
+	}
+	redirectToKeyView(w, r, key, "hash", "")
+}
+
+func deleteHashField(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	field := r.PathValue("field")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		redirectToKeyView(w, r, key, "hash", "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	err = client.Do(ctx, client.B().Hdel().Key(key).Field(field).Build()).Error()
+	if err != nil {
+		log.Printf("Failed to delete field %v of hash %v, err = %v\n", field, key, valkeyErr(err))
+		redirectToKeyView(w, r, key, "hash", fmt.Sprintf("failed to delete field %v", field))
+		return
+	}
+	redirectToKeyView(w, r, key, "hash", "")
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// number of previous values kept per key, see VALKEY_HISTORY_DEPTH
+var historyDepth int64 = 10
+
+type HistoryEntry struct {
+	Value string `json:"value"`
+	// time the value was replaced
+	Time string `json:"time"`
+}
+
+func historyEnabled() bool {
+	return os.Getenv("VALKEY_VALUE_HISTORY") == "true"
+}
+
+func historyKey(key string) string {
+	return internalKey("history", key)
+}
+
+// set the value of a key
+// with VALKEY_VALUE_HISTORY the replaced value is kept in the history list of the key
+func setValue(ctx context.Context, client ValkeyClient, key string, value string) error {
+	_, err := setValueAndWait(ctx, client, key, value, 0, 0)
+	return err
+}
+
+// set the value of a key and wait up to timeout until replicas replicas acknowledged the write
+// answers the number of acknowledging replicas, WAIT is skipped for replicas < 1
+func setValueAndWait(ctx context.Context, client ValkeyClient, key string, value string, replicas int64, timeout time.Duration) (int64, error) {
+	history := historyEnabled()
+	// WAIT only covers the writes of its own connection, DoMulti sends all commands over the same one
+	cmds := make(valkey.Commands, 0, 3)
+	if history {
+		cmds = append(cmds, client.B().Get().Key(key).Build())
+	}
+	cmds = append(cmds, client.B().Set().Key(key).Value(value).Build())
+	if replicas > 0 {
+		cmds = append(cmds, client.B().Wait().Numreplicas(replicas).Timeout(timeout.Milliseconds()).Build())
+	}
+	resps := client.DoMulti(ctx, cmds...)
+
+	set := 0
+	if history {
+		set = 1
+	}
+	if err := resps[set].Error(); err != nil {
+		return 0, err
+	}
+	// neither the previous value nor the acknowledgements are known in a dry run
+	if skippedByDryRun(resps[set]) {
+		return replicas, nil
+	}
+	var replicated int64
+	if replicas > 0 {
+		var err error
+		replicated, err = resps[set+1].AsInt64()
+		if err != nil {
+			return 0, err
+		}
+	}
+	if !history {
+		return replicated, nil
+	}
+
+	previous, err := resps[0].ToString()
+	if valkey.IsValkeyNil(err) {
+		// nothing replaced
+		return replicated, nil
+	}
+	if err != nil {
+		log.Printf("Failed to fetch previous value of key %v, err = %v\n", key, valkeyErr(err))
+		return replicated, nil
+	}
+
+	data, err := json.Marshal(HistoryEntry{Value: previous, Time: time.Now().UTC().Format(time.RFC3339)})
+	if err != nil {
+		return replicated, err
+	}
+	for _, resp := range client.DoMulti(ctx,
+		client.B().Lpush().Key(historyKey(key)).Element(string(data)).Build(),
+		client.B().Ltrim().Key(historyKey(key)).Start(0).Stop(historyDepth-1).Build(),
+	) {
+		if err := resp.Error(); err != nil {
+			log.Printf("Failed to store history of key %v, err = %v\n", key, valkeyErr(err))
+			break
+		}
+	}
+	return replicated, nil
+}
+
+// previous values of a key, newest first
+func getHistory(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	elements, err := client.Do(ctx, client.B().Lrange().Key(historyKey(key)).Start(0).Stop(-1).Build()).AsStrSlice()
+	if err != nil {
+		log.Printf("Failed to fetch history of key %v, err = %v\n", key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch history of key %v", key))
+		return
+	}
+
+	history := make([]HistoryEntry, 0, len(elements))
+	for _, element := range elements {
+		var entry HistoryEntry
+		if err := json.Unmarshal([]byte(element), &entry); err != nil {
+			log.Printf("Skipping invalid history entry of key %v: %v", key, err)
+			continue
+		}
+		history = append(history, entry)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"enabled": historyEnabled(),
+		"history": history,
+	})
+}
+
+// read VALKEY_HISTORY_DEPTH
+func initHistory() {
+	depthStr := os.Getenv("VALKEY_HISTORY_DEPTH")
+	if len(depthStr) < 1 {
+		return
+	}
+	depth, err := strconv.ParseInt(depthStr, 10, 64)
+	if err != nil || depth < 1 {
+		log.Printf("Ignoring VALKEY_HISTORY_DEPTH=%v, expected a positive integer\n", depthStr)
+		return
+	}
+	historyDepth = depth
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type HyperLogLogViewModel struct {
+	Key         string
+	Cardinality int64
+}
+
+// split the elements of the new key form, one per line
+func formElements(value string) []string {
+	elements := make([]string, 0)
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			elements = append(elements, line)
+		}
+	}
+	return elements
+}
+
+// estimated cardinality of a HyperLogLog
+func renderHyperLogLog(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		renderError(w, r, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	// PFCOUNT answers 0 for missing keys
+	exists, err := client.Do(ctx, client.B().Exists().Key(key).Build()).AsInt64()
+	if err != nil {
+		log.Printf("Failed to check key %v, err = %v\n", key, valkeyErr(err))
+		renderError(w, r, http.StatusBadGateway, fmt.Sprintf("failed to check key %v", key))
+		return
+	}
+	if exists == 0 {
+		renderError(w, r, http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return
+	}
+
+	count, err := client.Do(ctx, client.B().Pfcount().Key(key).Build()).AsInt64()
+	if err != nil {
+		log.Printf("Failed to count key %v, err = %v\n", key, valkeyErr(err))
+		renderError(w, r, http.StatusBadGateway, fmt.Sprintf("failed to count key %v, is it a HyperLogLog?", key))
+		return
+	}
+
+	renderTemplate(w, "hyperloglog", "base", HyperLogLogViewModel{Key: key, Cardinality: count})
+}
+
+// merge HyperLogLogs into destination, an existing destination is merged as well
+func mergeHyperLogLogs(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Destination string   `json:"destination"`
+		Sources     []string `json:"sources"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if len(body.Destination) < 1 || len(body.Sources) < 1 {
+		writeJSONError(w, http.StatusBadRequest, "destination and sources are required")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	resps := client.DoMulti(ctx,
+		client.B().Pfmerge().Destkey(body.Destination).Sourcekey(body.Sources...).Build(),
+		client.B().Pfcount().Key(body.Destination).Build(),
+	)
+	if err := resps[0].Error(); err != nil {
+		log.Printf("Failed to merge %v into %v, err = %v\n", body.Sources, body.Destination, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to merge into %v", body.Destination))
+		return
+	}
+	count, err := replyInt64(resps[1])
+	if err != nil {
+		log.Printf("Failed to count key %v, err = %v\n", body.Destination, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to count key %v", body.Destination))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"destination": body.Destination,
+		"cardinality": count,
+	})
+}
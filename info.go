@@ -0,0 +1,21 @@
+package main
+
+import (
+	"strings"
+)
+
+// parse the "field:value" lines of an INFO response, section headers are skipped
+func parseInfo(info string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) < 1 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, found := strings.Cut(line, ":")
+		if found {
+			fields[name] = value
+		}
+	}
+	return fields
+}
@@ -0,0 +1,237 @@
+//go:build integration
+
+package main_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	app "github.com/anynines/a9s-keyvalue-app"
+)
+
+// run with go test -tags integration, needs a Docker daemon
+const (
+	valkeyImage    = "valkey/valkey:8"
+	valkeyPassword = "integration"
+)
+
+// the app under test, its handlers connect to the container
+var server *httptest.Server
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+// start Valkey and the app, the container is stopped once the tests ran
+func run(m *testing.M) int {
+	ctx := context.Background()
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        valkeyImage,
+			ExposedPorts: []string{"6379/tcp"},
+			Cmd:          []string{"valkey-server", "--requirepass", valkeyPassword},
+			WaitingFor:   wait.ForListeningPort("6379/tcp"),
+		},
+		Started: true,
+	})
+	if err != nil {
+		log.Printf("Failed to start Valkey: %v\n", err)
+		return 1
+	}
+	defer func() {
+		if err := container.Terminate(ctx); err != nil {
+			log.Printf("Failed to stop Valkey: %v\n", err)
+		}
+	}()
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	port, err := container.MappedPort(ctx, "6379/tcp")
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	// the handlers read the credentials of the local setup
+	os.Setenv("VALKEY_HOST", host)
+	os.Setenv("VALKEY_PORT", port.Port())
+	os.Setenv("VALKEY_USERNAME", "default")
+	os.Setenv("VALKEY_PASSWORD", valkeyPassword)
+
+	app.InitTemplates()
+	app.RegisterRoutes(".")
+	server = httptest.NewServer(app.RootHandler())
+	defer server.Close()
+
+	return m.Run()
+}
+
+func TestNewClient(t *testing.T) {
+	client, err := app.NewClient(nil)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer client.Close()
+
+	pong, err := client.Do(context.Background(), client.B().Ping().Build()).ToString()
+	if err != nil || pong != "PONG" {
+		t.Errorf("PING = %v, %v, want PONG", pong, err)
+	}
+}
+
+func TestKeyValueLifecycle(t *testing.T) {
+	const key, value = "integration_key", "integration_value"
+
+	body := fmt.Sprintf(`{"key":%q,"value":%q}`, key, value)
+	resp := do(t, http.MethodPost, "/api/v1/key-values", body)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create status = %v, want %v", resp.StatusCode, http.StatusCreated)
+	}
+
+	if html := get(t, "/"); !strings.Contains(html, key) || !strings.Contains(html, value) {
+		t.Errorf("index does not list %v=%v:\n%v", key, value, html)
+	}
+	if exported := export(t); len(exported) != 1 || exported[0].Key != key || exported[0].Value != value {
+		t.Errorf("export = %v, want %v=%v", exported, key, value)
+	}
+
+	resp = do(t, http.MethodDelete, "/api/v1/key-values/"+key, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("delete status = %v, want %v", resp.StatusCode, http.StatusOK)
+	}
+
+	if html := get(t, "/"); strings.Contains(html, key) {
+		t.Errorf("index still lists %v:\n%v", key, html)
+	}
+	if exported := export(t); len(exported) != 0 {
+		t.Errorf("export = %v, want no keys", exported)
+	}
+
+	resp = do(t, http.MethodDelete, "/api/v1/key-values/"+key, "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("second delete status = %v, want %v", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+// compares a client dialed per request, single, with the shared client of the cluster, pooled
+func BenchmarkCreateKeyValue(b *testing.B) {
+	b.Run("single", func(b *testing.B) {
+		restore := app.DialPerRequest()
+		defer restore()
+		benchmarkCreateKeyValue(b)
+	})
+	b.Run("pooled", benchmarkCreateKeyValue)
+}
+
+func benchmarkCreateKeyValue(b *testing.B) {
+	var next atomic.Int64
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			form := url.Values{"key": {fmt.Sprintf("bench_key_%d", next.Add(1))}, "value": {"bench"}}
+			r := httptest.NewRequest(http.MethodPost, "/key-values/create", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			app.CreateKeyValue(w, r)
+			if w.Code != http.StatusFound {
+				b.Errorf("status = %v, want %v", w.Code, http.StatusFound)
+			}
+		}
+	})
+	b.StopTimer()
+
+	if deleted := deleteMatching(b, "bench_key_*"); deleted != next.Load() {
+		b.Errorf("deleted %v keys, want %v", deleted, next.Load())
+	}
+}
+
+// SCAN and DEL the keys matching pattern, returns the number of deleted keys
+func deleteMatching(b *testing.B, pattern string) int64 {
+	b.Helper()
+	client, err := app.NewClient(nil)
+	if err != nil {
+		b.Fatalf("NewClient() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	var deleted int64
+	var cursor uint64
+	for {
+		entry, err := client.Do(ctx, client.B().Scan().Cursor(cursor).Match(pattern).Count(1000).Build()).AsScanEntry()
+		if err != nil {
+			b.Fatalf("SCAN %v: %v", pattern, err)
+		}
+		if len(entry.Elements) > 0 {
+			n, err := client.Do(ctx, client.B().Del().Key(entry.Elements...).Build()).AsInt64()
+			if err != nil {
+				b.Fatalf("DEL: %v", err)
+			}
+			deleted += n
+		}
+		cursor = entry.Cursor
+		if cursor == 0 {
+			return deleted
+		}
+	}
+}
+
+func do(t *testing.T, method, path, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func get(t *testing.T, path string) string {
+	t.Helper()
+	resp, err := http.Get(server.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %v status = %v, want %v", path, resp.StatusCode, http.StatusOK)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func export(t *testing.T) []app.KeyValue {
+	t.Helper()
+	var keyValues []app.KeyValue
+	if err := json.Unmarshal([]byte(get(t, "/api/v1/key-values/export")), &keyValues); err != nil {
+		t.Fatalf("invalid export: %v", err)
+	}
+	return keyValues
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+type ListElement struct {
+	Index int
+	Value string
+}
+
+type ListViewModel struct {
+	Key      string
+	Length   int64
+	Elements []ListElement
+	// message of a failed form submission, see redirectToKeyView
+	Error string
+}
+
+// elements of a list with their index and forms to change it
+func renderList(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		renderError(w, r, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	resps := client.DoMulti(ctx,
+		client.B().Llen().Key(key).Build(),
+		client.B().Lrange().Key(key).Start(0).Stop(-1).Build(),
+	)
+	viewModel := ListViewModel{Key: key, Error: r.URL.Query().Get("error")}
+	if viewModel.Length, err = resps[0].AsInt64(); err != nil {
+		log.Printf("Failed to fetch length of list %v, err = %v\n", key, valkeyErr(err))
+		renderError(w, r, http.StatusBadGateway, fmt.Sprintf("failed to fetch list %v", key))
+		return
+	}
+	elements, err := resps[1].AsStrSlice()
+	if err != nil {
+		log.Printf("Failed to fetch elements of list %v, err = %v\n", key, valkeyErr(err))
+		renderError(w, r, http.StatusBadGateway, fmt.Sprintf("failed to fetch list %v", key))
+		return
+	}
+	for index, value := range elements {
+		viewModel.Elements = append(viewModel.Elements, ListElement{Index: index, Value: value})
+	}
+
+	renderTemplate(w, "list", "base", viewModel)
+}
+
+// replace the element at an index
+func setListElement(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	r.ParseForm()
+	index, err := strconv.ParseInt(r.PostFormValue("index"), 10, 64)
+	if err != nil {
+		redirectToKeyView(w, r, key, "list", fmt.Sprintf("invalid index %v", r.PostFormValue("index")))
+		return
+	}
+	value := r.PostFormValue("value")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		redirectToKeyView(w, r, key, "list", "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	err = client.Do(ctx, client.B().Lset().Key(key).Index(index).Element(value).Build()).Error()
+	if err != nil && strings.Contains(err.Error(), "out of range") {
+		redirectToKeyView(w, r, key, "list", fmt.Sprintf("index %v is out of range", index))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to set index %v of list %v, err = %v\n", index, key, valkeyErr(err))
+		redirectToKeyView(w, r, key, "list", fmt.Sprintf("failed to set index %v", index))
+		return
+	}
+	redirectToKeyView(w, r, key, "list", "")
+}
+
+// remove the first (end=left) or last (end=right) element
+func popListElement(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	r.ParseForm()
+	end := r.PostFormValue("end")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		redirectToKeyView(w, r, key, "list", "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	cmd := client.B().Lpop().Key(key).Build()
+	if end == "right" {
+		cmd = client.B().Rpop().Key(key).Build()
+	}
+	if err := client.Do(ctx, cmd).Error(); err != nil && !valkey.IsValkeyNil(err) {
+		log.Printf("Failed to pop from list %v, err = %v\n", key, valkeyErr(err))
+		redirectToKeyView(w, r, key, "list", "failed to pop an element")
+		return
+	}
+	redirectToKeyView(w, r, key, "list", "")
+}
+
+// insert an element before or after the first occurrence of a pivot element
+func insertListElement(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	r.ParseForm()
+	pivot := r.PostFormValue("pivot")
+	value := r.PostFormValue("value")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		redirectToKeyView(w, r, key, "list", "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	cmd := client.B().Linsert().Key(key).Before().Pivot(pivot).Element(value).Build()
+	if r.PostFormValue("position") == "after" {
+		cmd = client.B().Linsert().Key(key).After().Pivot(pivot).Element(value).Build()
+	}
+	length, err := replyInt64(client.Do(ctx, cmd))
+	if err != nil {
+		log.Printf("Failed to insert into list %v, err = %v\n", key, valkeyErr(err))
+		redirectToKeyView(w, r, key, "list", "failed to insert the element")
+		return
+	}
+	switch {
+	case length == 0:
+		redirectToKeyView(w, r, key, "list", fmt.Sprintf("key %v not found", key))
+		return
+	case length < 0:
+		redirectToKeyView(w, r, key, "list", fmt.Sprintf("pivot %v not found", pivot))
+		return
+	}
+	redirectToKeyView(w, r, key, "list", "")
+}
+
+// keep only the elements from start to stop, both inclusive
+func trimList(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	r.ParseForm()
+	start, err := strconv.ParseInt(r.PostFormValue("start"), 10, 64)
+	if err != nil {
+		redirectToKeyView(w, r, key, "list", fmt.Sprintf("invalid start %v", r.PostFormValue("start")))
+		return
+	}
+	stop, err := strconv.ParseInt(r.PostFormValue("stop"), 10, 64)
+	if err != nil {
+		redirectToKeyView(w, r, key, "list", fmt.Sprintf("invalid stop %v", r.PostFormValue("stop")))
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		redirectToKeyView(w, r, key, "list", "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	err = client.Do(ctx, client.B().Ltrim().Key(key).Start(start).Stop(stop).Build()).Error()
+	if err != nil {
+		log.Printf("Failed to trim list %v, err = %v\n", key, valkeyErr(err))
+		redirectToKeyView(w, r, key, "list", "failed to trim the list")
+		return
+	}
+	redirectToKeyView(w, r, key, "list", "")
+}
+
+// positions of the matching elements like LPOS for servers without it
+// a negative rank searches from the tail, count 0 returns all matches
+func searchListElements(elements []string, element string, rank int64, count int64) []int64 {
+	positions := make([]int64, 0)
+	skip := max(rank, -rank) - 1
+	for i := range elements {
+		index := i
+		if rank < 0 {
+			index = len(elements) - 1 - i
+		}
+		if elements[index] != element {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		positions = append(positions, int64(index))
+		if count > 0 && int64(len(positions)) >= count {
+			break
+		}
+	}
+	return positions
+}
+
+// LPOS with ?element=, ?rank= and ?count=, positions is null if the element is not found
+// servers before 6.0.6 lack LPOS, the list is searched with LRANGE instead
+func searchList(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	query := r.URL.Query()
+
+	element := query.Get("element")
+	if len(element) < 1 {
+		writeJSONError(w, http.StatusBadRequest, "query parameter element is required")
+		return
+	}
+	var rank int64 = 1
+	if rankStr := query.Get("rank"); len(rankStr) > 0 {
+		var err error
+		rank, err = strconv.ParseInt(rankStr, 10, 64)
+		if err != nil || rank == 0 {
+			writeJSONError(w, http.StatusBadRequest, "query parameter rank must be a non-zero integer")
+			return
+		}
+	}
+	var count int64
+	if countStr := query.Get("count"); len(countStr) > 0 {
+		var err error
+		count, err = strconv.ParseInt(countStr, 10, 64)
+		if err != nil || count < 0 {
+			writeJSONError(w, http.StatusBadRequest, "query parameter count must be a non-negative integer")
+			return
+		}
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	response := make(map[string]interface{})
+	positions, err := client.Do(ctx, client.B().Lpos().Key(key).Element(element).Rank(rank).Count(count).Build()).AsIntSlice()
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "unknown command") {
+		var elements []string
+		elements, err = client.Do(ctx, client.B().Lrange().Key(key).Start(0).Stop(-1).Build()).AsStrSlice()
+		positions = searchListElements(elements, element, rank, count)
+		response["fallback"] = "LPOS requires Valkey 6.0.6 or later, the list was searched with LRANGE"
+	}
+	if err != nil {
+		log.Printf("Failed to search list %v, err = %v\n", key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to search list %v", key))
+		return
+	}
+
+	response["positions"] = positions
+	if len(positions) < 1 {
+		response["positions"] = nil
+	}
+	writeJSON(w, http.StatusOK, response)
+}
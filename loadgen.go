@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// limits of a single load generator run
+const (
+	loadGeneratorMaxCount     = 100000
+	loadGeneratorMaxValueSize = 1 << 20
+	loadGeneratorBatchSize    = 1000
+)
+
+// suffix of the generated keys, keeps them unique across runs of the same process
+var loadGeneratorSeq atomic.Int64
+
+const loadGeneratorAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func randomValue(size int) string {
+	value := make([]byte, size)
+	for i := range value {
+		value[i] = loadGeneratorAlphabet[rand.IntN(len(loadGeneratorAlphabet))]
+	}
+	return string(value)
+}
+
+// seed the instance with test data, the SETs are pipelined in batches
+func loadGenerator(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		Count          int    `json:"count"`
+		KeyPrefix      string `json:"key_prefix"`
+		ValueSizeBytes int    `json:"value_size_bytes"`
+		TTLSeconds     int64  `json:"ttl_seconds"`
+	}{KeyPrefix: "test_", ValueSizeBytes: 100}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if body.Count < 1 || body.Count > loadGeneratorMaxCount {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("count must be between 1 and %v", loadGeneratorMaxCount))
+		return
+	}
+	if body.ValueSizeBytes < 0 || body.ValueSizeBytes > loadGeneratorMaxValueSize {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("value_size_bytes must be between 0 and %v", loadGeneratorMaxValueSize))
+		return
+	}
+	if body.TTLSeconds < 0 {
+		writeJSONError(w, http.StatusBadRequest, "ttl_seconds must not be negative")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	start := time.Now()
+	generated := 0
+	for generated < body.Count {
+		batch := min(loadGeneratorBatchSize, body.Count-generated)
+		cmds := make([]valkey.Completed, batch)
+		for i := range cmds {
+			key := body.KeyPrefix + strconv.FormatInt(loadGeneratorSeq.Add(1), 10)
+			if body.TTLSeconds > 0 {
+				cmds[i] = client.B().Set().Key(key).Value(randomValue(body.ValueSizeBytes)).ExSeconds(body.TTLSeconds).Build()
+			} else {
+				cmds[i] = client.B().Set().Key(key).Value(randomValue(body.ValueSizeBytes)).Build()
+			}
+		}
+
+		// every batch gets its own deadline, large runs take longer than a single request
+		ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+		resps := client.DoMulti(ctx, cmds...)
+		cancel()
+		for _, resp := range resps {
+			if err := resp.Error(); err != nil {
+				log.Printf("Failed to generate keys after %v, err = %v\n", generated, valkeyErr(err))
+				writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to generate keys after %v", generated))
+				return
+			}
+			generated++
+		}
+	}
+
+	duration := time.Since(start)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"generated":          generated,
+		"duration_ms":        duration.Milliseconds(),
+		"throughput_per_sec": int64(float64(generated) / duration.Seconds()),
+	})
+}
@@ -4,15 +4,20 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"time"
 
 	"github.com/valkey-io/valkey-go"
 )
@@ -27,17 +32,53 @@ type ValkeyCredentials struct {
 	Host          string        `json:"host"`
 	CaCertificate *string       `json:"cacrt"`
 	Valkey        ValkeyDetails `json:"valkey"`
+	// only set from the environment, see sentinelFromEnv
+	Sentinel SentinelDetails `json:"-"`
+}
+
+// address and mode for the logs, the passwords are left out
+func (c ValkeyCredentials) String() string {
+	mode := "standalone"
+	if len(c.Sentinel.MasterSet) > 0 {
+		mode = "sentinel, master set " + c.Sentinel.MasterSet
+	}
+	tls := ""
+	if c.CaCertificate != nil {
+		tls = ", TLS"
+	}
+	return fmt.Sprintf("%v:%v as %v (%v%v)", c.Host, c.Valkey.Port, c.Valkey.Username, mode, tls)
 }
 
 type ServiceInstance struct {
-	Credentials ValkeyCredentials `json:"credentials"`
+	Name string `json:"name"`
+	// shape depends on the service version, see parseVcapCredentials
+	Credentials json.RawMessage `json:"credentials"`
 }
 
 type VcapServices map[string][]ServiceInstance
 
+type IndexViewModel struct {
+	// bookmarked keys pinned above the other keys
+	Bookmarks    []KeyValue
+	KeyValues    []KeyValue
+	KeyCount     int64
+	ShowInternal bool
+	Tag          string
+	// seconds between reloads of the index, 0 disables them
+	AutoRefresh int
+}
+
 type KeyValue struct {
-	Key   string
-	Value string
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Valkey type of keys which are no strings, these have no value
+	Type string `json:"type,omitempty"`
+	// display helpers for the html views, see displayKeyValue
+	Format     string        `json:"-"`
+	Hex        string        `json:"-"`
+	Decoded    string        `json:"-"`
+	Pretty     template.HTML `json:"-"`
+	Bookmarked bool          `json:"-"`
 }
 
 // template store
@@ -48,37 +89,90 @@ func initTemplates() {
 	if templates == nil {
 		templates = make(map[string]*template.Template)
 	}
-	templates["index"] = template.Must(template.ParseFiles("templates/index.html", "templates/base.html"))
-	templates["new"] = template.Must(template.ParseFiles("templates/new.html", "templates/base.html"))
+	templates["index"] = parseTemplates("index.html", "base.html")
+	templates["new"] = parseTemplates("new.html", "base.html")
+	templates["compare"] = parseTemplates("compare.html", "base.html")
+	templates["detail"] = parseTemplates("detail.html", "base.html")
+	templates["hyperloglog"] = parseTemplates("hyperloglog.html", "base.html")
+	templates["geo"] = parseTemplates("geo.html", "base.html")
+	templates["hash"] = parseTemplates("hash.html", "base.html")
+	templates["list"] = parseTemplates("list.html", "base.html")
+	templates["set"] = parseTemplates("set.html", "base.html")
+	templates["zset"] = parseTemplates("zset.html", "base.html")
+	templates["stream"] = parseTemplates("stream.html", "base.html")
+	templates["edit"] = parseTemplates("edit.html", "base.html")
+	templates["error"] = parseTemplates("error.html", "base.html")
+}
+
+// helpers available in all templates
+var templateFuncs = template.FuncMap{
+	// keys in URL paths, html/template leaves slashes and question marks alone
+	"pathEscape": url.PathEscape,
+	// to hide links to disabled features
+	"features": func() FeatureFlags { return features },
+}
+
+// built-in templates, used without APP_TEMPLATE_DIR
+//
+//go:embed templates/*.html
+var embeddedTemplates embed.FS
+
+// directory of custom templates, see APP_TEMPLATE_DIR
+var templateDir string
+
+func parseTemplates(files ...string) *template.Template {
+	tmpl := template.New(files[0]).Funcs(templateFuncs)
+	paths := make([]string, len(files))
+	if len(templateDir) > 0 {
+		for i, file := range files {
+			paths[i] = filepath.Join(templateDir, file)
+		}
+		return template.Must(tmpl.ParseFiles(paths...))
+	}
+	for i, file := range files {
+		paths[i] = "templates/" + file
+	}
+	return template.Must(tmpl.ParseFS(embeddedTemplates, paths...))
+}
+
+// VALKEY_ENV_PREFIX avoids collisions with other apps, e.g. MY_APP_VALKEY reads MY_APP_VALKEY_HOST
+func envPrefix() string {
+	prefix := os.Getenv("VALKEY_ENV_PREFIX")
+	if len(prefix) < 1 {
+		return "VALKEY"
+	}
+	return prefix
 }
 
 func createCredentials() (ValkeyCredentials, error) {
+	prefix := envPrefix()
+
 	// Local
 	if os.Getenv("VCAP_SERVICES") == "" {
-		host := os.Getenv("VALKEY_HOST")
+		host := os.Getenv(fmt.Sprintf("%s_%s", prefix, "HOST"))
 		if len(host) < 1 {
-			err := fmt.Errorf("environment variable VALKEY_HOST not set")
+			err := fmt.Errorf("environment variable %s_%s not set", prefix, "HOST")
 			log.Println(err)
 			return ValkeyCredentials{}, err
 		}
 
-		password := os.Getenv("VALKEY_PASSWORD")
+		password := os.Getenv(fmt.Sprintf("%s_%s", prefix, "PASSWORD"))
 		if len(password) < 1 {
-			err := fmt.Errorf("environment variable VALKEY_PASSWORD not set")
+			err := fmt.Errorf("environment variable %s_%s not set", prefix, "PASSWORD")
 			log.Println(err)
 			return ValkeyCredentials{}, err
 		}
 
-		username := os.Getenv("VALKEY_USERNAME")
+		username := os.Getenv(fmt.Sprintf("%s_%s", prefix, "USERNAME"))
 		if len(username) < 1 {
-			err := fmt.Errorf("environment variable VALKEY_USERNAME not set")
+			err := fmt.Errorf("environment variable %s_%s not set", prefix, "USERNAME")
 			log.Println(err)
 			return ValkeyCredentials{}, err
 		}
 
-		portStr := os.Getenv("VALKEY_PORT")
+		portStr := os.Getenv(fmt.Sprintf("%s_%s", prefix, "PORT"))
 		if len(portStr) < 1 {
-			err := fmt.Errorf("environment variable VALKEY_PORT not set")
+			err := fmt.Errorf("environment variable %s_%s not set", prefix, "PORT")
 			log.Println(err)
 			return ValkeyCredentials{}, err
 		}
@@ -96,6 +190,7 @@ func createCredentials() (ValkeyCredentials, error) {
 				Port:     port,
 				Username: username,
 			},
+			Sentinel: sentinelFromEnv(prefix),
 		}
 		return credentials, nil
 	}
@@ -109,15 +204,31 @@ func createCredentials() (ValkeyCredentials, error) {
 		return ValkeyCredentials{}, err
 	}
 
-	for _, instances := range vcapServices {
-		for _, instance := range instances {
-			return instance.Credentials, nil
+	// VALKEY_SERVICE_NAME or VALKEY_SERVICE_INDEX pick one of several bound instances
+	name, indexStr := os.Getenv("VALKEY_SERVICE_NAME"), os.Getenv("VALKEY_SERVICE_INDEX")
+	if len(name) > 0 || len(indexStr) > 0 {
+		instance, err := selectServiceInstance(vcapServices, name, indexStr)
+		if err != nil {
+			log.Println(err)
+			return ValkeyCredentials{}, err
+		}
+		credentials, err := parseVcapCredentials(instance.Credentials)
+		if err != nil {
+			err = fmt.Errorf("invalid credentials of service instance %v: %w", instance.Name, err)
+			log.Println(err)
+			return ValkeyCredentials{}, err
 		}
+		credentials.Sentinel = sentinelFromEnv(prefix)
+		return credentials, nil
 	}
 
-	err = fmt.Errorf("no valid services found in VCAP_SERVICES")
-	log.Println(err)
-	return ValkeyCredentials{}, err
+	credentials, err := defaultServiceCredentials(vcapServices)
+	if err != nil {
+		log.Println(err)
+		return ValkeyCredentials{}, err
+	}
+	credentials.Sentinel = sentinelFromEnv(prefix)
+	return credentials, nil
 }
 
 func renderTemplate(w http.ResponseWriter, name string, template string, viewModel interface{}) {
@@ -128,18 +239,49 @@ func renderTemplate(w http.ResponseWriter, name string, template string, viewMod
 	}
 }
 
-func NewClient() (valkey.Client, error) {
-	credentials, err := createCredentials()
+// database selected by the clients
+var valkeyDB = 0
+
+// send read-only commands to replicas, see VALKEY_READ_REPLICA_ONLY
+var readFromReplicas bool
+
+// timeout of the Valkey operations of a request, see VALKEY_CMD_TIMEOUT
+var valkeyCmdTimeout = 10 * time.Second
+
+func withDeadline(parent context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, d)
+}
+
+// point out timeouts in log messages, the error is reported by /health as well
+func valkeyErr(err error) error {
+	setLastError(err)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("deadline of %v exceeded: %w", valkeyCmdTimeout, err)
+	}
+	return err
+}
+
+// connect to the given cluster, without VALKEY_CLUSTERS to the bound or configured instance
+// credentials of the cluster, nil for the single instance configuration
+func clusterCredentials(cluster *ClusterConfig) (ValkeyCredentials, error) {
+	if cluster != nil {
+		return cluster.credentials(), nil
+	}
+	return createCredentials()
+}
+
+func NewClient(cluster *ClusterConfig) (valkey.Client, error) {
+	credentials, err := clusterCredentials(cluster)
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("Connection to:\n%v\n", credentials)
+	log.Printf("Connection to %v\n", credentials)
 
 	clientOptions := valkey.ClientOption{
 		InitAddress: []string{fmt.Sprintf("%v:%v", credentials.Host, credentials.Valkey.Port)},
 		Username:    credentials.Valkey.Username,
 		Password:    credentials.Valkey.Password,
-		SelectDB:    0,
+		SelectDB:    valkeyDB,
 	}
 
 	if credentials.CaCertificate != nil {
@@ -154,6 +296,54 @@ func NewClient() (valkey.Client, error) {
 		}
 	}
 
+	if sentinel, ok := credentials.Sentinel.option(); ok {
+		// the sentinels use the TLS setup of the data nodes
+		sentinel.TLSConfig = clientOptions.TLSConfig
+		clientOptions.Sentinel = sentinel
+	}
+
+	// RESP3 is tried first by default, it provides typed replies and push messages
+	// which server-side keyspace notifications and client side caching rely on
+	switch respVersion := os.Getenv("VALKEY_RESP_VERSION"); respVersion {
+	case "", "3":
+	case "2":
+		clientOptions.AlwaysRESP2 = true
+	default:
+		log.Printf("Ignoring VALKEY_RESP_VERSION=%v, expected 2 or 3\n", respVersion)
+	}
+
+	// the single client does not follow MOVED redirects, they reach the handlers as errors
+	if os.Getenv("VALKEY_DISABLE_MOVED_REDIRECT") == "true" {
+		clientOptions.ForceSingleClient = true
+	}
+
+	if readFromReplicas {
+		clientOptions.SendToReplicas = func(cmd valkey.Completed) bool { return cmd.IsReadOnly() }
+	}
+
+	maxRetries := 3
+	if retriesStr := os.Getenv("VALKEY_MAX_RETRIES"); len(retriesStr) > 0 {
+		retries, err := strconv.Atoi(retriesStr)
+		if err != nil || retries < 0 {
+			log.Printf("Ignoring VALKEY_MAX_RETRIES=%v, expected a non-negative number\n", retriesStr)
+		} else {
+			maxRetries = retries
+		}
+	}
+	if maxRetries == 0 {
+		clientOptions.DisableRetry = true
+	} else {
+		clientOptions.RetryDelay = retryDelay(maxRetries,
+			durationFromEnv("VALKEY_MIN_RETRY_BACKOFF", 8*time.Millisecond),
+			durationFromEnv("VALKEY_MAX_RETRY_BACKOFF", 512*time.Millisecond))
+	}
+
+	poolConfig.apply(&clientOptions)
+
+	if maxLifetime := durationFromEnv("VALKEY_CONN_MAX_LIFETIME", 0); maxLifetime > 0 {
+		clientOptions.DialFn = dialWithMaxLifetime(maxLifetime)
+	}
+
 	client, err := valkey.NewClient(clientOptions)
 
 	return client, err
@@ -162,69 +352,262 @@ func NewClient() (valkey.Client, error) {
 // create KV pair
 func createKeyValue(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
+	// the entry ID is shown after the redirect, so the stream entry is added first
+	if r.PostFormValue("type") == typeStream && features.Streams {
+		createStreamEntry(w, r)
+		return
+	}
 	key := r.PostFormValue("key")
 	value := r.PostFormValue("value")
+	// ?replicas=<n>&timeout_ms=<ms> waits for the replication, see createKeyValueAPI
+	replicas, timeout, err := parseWaitParams(r)
+	if err != nil {
+		renderError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	http.Redirect(w, r, "/", http.StatusFound)
 
 	// insert key value into service
-	client, err := NewClient()
+	client, err := newClient(r)
 	if err != nil {
 		log.Printf("Failed to create connection: %v", err)
 		return
 	}
 	defer client.Close()
 
-	ctx := context.Background()
-	err = client.Do(ctx, client.B().Set().Key(key).Value(value).Build()).Error()
+	// WAIT blocks up to the timeout on top of the commands
+	ctx, cancel := withDeadline(r.Context(), timeout+valkeyCmdTimeout)
+	defer cancel()
+
+	// the other types are created from the form fields of their type, see new.html
+	switch r.PostFormValue("type") {
+	case "hyperloglog":
+		err = client.Do(ctx, client.B().Pfadd().Key(key).Element(formElements(value)...).Build()).Error()
+		if err != nil {
+			log.Printf("Failed to add elements to HyperLogLog %v, err = %v\n", key, valkeyErr(err))
+		}
+		return
+	case typeGeo:
+		err = addGeoMember(ctx, client, key, r.PostFormValue("longitude"), r.PostFormValue("latitude"), r.PostFormValue("member"))
+		if err != nil {
+			log.Printf("Failed to add member to geo key %v, err = %v\n", key, valkeyErr(err))
+		}
+		return
+	}
+
+	if replicas > 0 {
+		// WAIT takes a connection of the blocking pool
+		release, err := acquireBlockingConn(ctx, selectedCluster(r))
+		if err != nil {
+			log.Printf("Failed to set key %v and value %v ; err = %v", key, value, err)
+			return
+		}
+		defer release()
+	}
+
+	replicated, err := setValueAndWait(ctx, client, key, value, replicas, timeout)
 	if err != nil {
-		log.Printf("Failed to set key %v and value %v ; err = %v", key, value, err)
+		log.Printf("Failed to set key %v and value %v ; err = %v", key, value, valkeyErr(err))
 		return
 	}
+	if replicated < replicas {
+		log.Printf("Only %v of %v replicas acknowledged key %v within %v\n", replicated, replicas, key, timeout)
+	}
 }
 
-func newKeyValue(w http.ResponseWriter, r *http.Request) {
-	renderTemplate(w, "new", "base", nil)
+type NewViewModel struct {
+	KeyValue
+	// empty for strings, otherwise e.g. "hyperloglog"
+	Type string
+	// ID of the stream entry added before, see createStreamEntry
+	EntryID string
 }
 
-func renderKeyValues(w http.ResponseWriter, r *http.Request) {
-	keyStore := make([]KeyValue, 0)
+// form for a new key value pair, ?key=<key> prefills the current value for editing
+// ?type=hyperloglog switches to the form of another type
+func newKeyValue(w http.ResponseWriter, r *http.Request) {
+	viewModel := NewViewModel{
+		KeyValue: KeyValue{Key: r.URL.Query().Get("key")},
+		Type:     r.URL.Query().Get("type"),
+		EntryID:  r.URL.Query().Get("entry_id"),
+	}
+	if len(viewModel.Key) < 1 || len(viewModel.Type) > 0 {
+		renderTemplate(w, "new", "base", viewModel)
+		return
+	}
 
-	credentials, err := createCredentials()
+	client, err := newClient(r)
 	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		renderError(w, r, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+	viewModel.Value, err = client.Do(ctx, client.B().Get().Key(viewModel.Key).Build()).ToString()
+	if err != nil && !valkey.IsValkeyNil(err) {
+		log.Printf("Failed to fetch value for key %v, err = %v\n", viewModel.Key, valkeyErr(err))
+		renderError(w, r, http.StatusBadGateway, fmt.Sprintf("failed to fetch value for key %v", viewModel.Key))
 		return
 	}
-	log.Printf("Connection to:\n%v\n", credentials)
+	renderTemplate(w, "new", "base", viewModel)
+}
+
+func renderKeyValues(w http.ResponseWriter, r *http.Request) {
+	keyStore := make([]KeyValue, 0)
+	// ?raw=true shows JSON values as stored
+	raw := r.URL.Query().Get("raw") == "true"
+	// ?internal=true includes the keys the app stores for itself
+	showInternal := r.URL.Query().Get("internal") == "true"
+	// ?tag=name only shows the keys labeled with the tag
+	tag := ""
+	if features.Tags {
+		tag = r.URL.Query().Get("tag")
+	}
 
-	client, err := NewClient()
+	client, err := newClient(r)
 	if err != nil {
 		log.Printf("Failed to create connection: %v", err)
+		renderError(w, r, http.StatusServiceUnavailable, "failed to connect to Valkey")
 		return
 	}
 	defer client.Close()
 
-	ctx := context.Background()
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	var tagged map[string]bool
+	if len(tag) > 0 {
+		keys, err := fetchTaggedKeys(ctx, client, tag)
+		if err != nil {
+			log.Printf("Failed to fetch keys of tag %v, err = %v\n", tag, valkeyErr(err))
+			renderError(w, r, http.StatusBadGateway, fmt.Sprintf("failed to fetch keys of tag %v", tag))
+			return
+		}
+		tagged = make(map[string]bool, len(keys))
+		for _, key := range keys {
+			tagged[key] = true
+		}
+	}
+
+	// the listing still works without the pinned section and geo badges
+	bookmarks, err := fetchBookmarks(ctx, client)
+	if err != nil {
+		log.Printf("Failed to fetch bookmarks, err = %v\n", valkeyErr(err))
+	}
+	geoKeys, err := fetchGeoKeys(ctx, client)
+	if err != nil {
+		log.Printf("Failed to fetch geo keys, err = %v\n", valkeyErr(err))
+	}
+
 	log.Printf("Collecting keys.\n")
-	// collect keys
-	keys, err := client.Do(ctx, client.B().Keys().Pattern("*").Build()).AsStrSlice()
+	// collect keys page by page, the values are fetched by the worker pool
+	pages := make(chan []string)
+	scanErr := make(chan error, 1)
+	go func() {
+		scanErr <- scanPages(ctx, client, pages)
+	}()
+	for keyValue := range workerPool(ctx, client, scanWorkers, pages) {
+		if !showInternal && isInternalKey(keyValue.Key) {
+			continue
+		}
+		if tagged != nil && !tagged[keyValue.Key] {
+			continue
+		}
+		if len(keyValue.Type) > 0 {
+			if keyValue.Type == "zset" && geoKeys[keyValue.Key] {
+				keyValue.Type = typeGeo
+			}
+			keyValue.Format = keyValue.Type
+		} else {
+			keyValue = displayKeyValue(keyValue.Key, keyValue.Value, raw)
+		}
+		keyValue.Bookmarked = bookmarks[keyValue.Key]
+		keyStore = append(keyStore, keyValue)
+	}
+	err = <-scanErr
 	if err != nil {
-		log.Printf("Failed to fetch keys, err = %v\n", err)
+		log.Printf("Failed to fetch keys, err = %v\n", valkeyErr(err))
+		renderError(w, r, http.StatusBadGateway, "failed to fetch keys")
 		return
 	}
-	for _, key := range keys {
-		value, err := client.Do(ctx, client.B().Get().Key(key).Build()).ToString()
-		if err != nil {
-			log.Printf("Failed to fetch value for key %v, err = %v\n", key, err)
+	sort.Slice(keyStore, func(i, j int) bool {
+		return keyStore[i].Key < keyStore[j].Key
+	})
+
+	pinned := make([]KeyValue, 0)
+	unpinned := make([]KeyValue, 0, len(keyStore))
+	for _, keyValue := range keyStore {
+		if keyValue.Bookmarked {
+			pinned = append(pinned, keyValue)
 		} else {
-			keyStore = append(keyStore, KeyValue{Key: key, Value: value})
+			unpinned = append(unpinned, keyValue)
 		}
 	}
 
-	renderTemplate(w, "index", "base", keyStore)
+	viewModel := IndexViewModel{
+		Bookmarks:    pinned,
+		KeyValues:    unpinned,
+		KeyCount:     currentKeyCount.Load(),
+		ShowInternal: showInternal,
+		Tag:          tag,
+		AutoRefresh:  int(autoRefresh.Seconds()),
+	}
+	renderTemplate(w, "index", "base", viewModel)
 }
 
+// interval of the index reloads, see AUTO_REFRESH_SECONDS
+var autoRefresh time.Duration
+
 func main() {
+	templateDir = os.Getenv("APP_TEMPLATE_DIR")
 	initTemplates()
+	valkeyCmdTimeout = durationFromEnv("VALKEY_CMD_TIMEOUT", valkeyCmdTimeout)
+	autoRefresh = durationFromEnv("AUTO_REFRESH_SECONDS", 0)
+	dryRun = os.Getenv("VALKEY_DRY_RUN") == "true"
+	useUnlink = os.Getenv("VALKEY_USE_UNLINK") != "false"
+	trustProxy = loadTrustProxy()
+	readFromReplicas = os.Getenv("VALKEY_READ_REPLICA_ONLY") == "true"
+	if readFromReplicas {
+		// valkey-go only routes by command in cluster mode
+		log.Printf("Read routing: read-only commands go to replicas, writes to the primary, in cluster mode only\n")
+	} else {
+		log.Printf("Read routing: all commands go to the primary\n")
+	}
+	if prefix := os.Getenv("VALKEY_INTERNAL_PREFIX"); len(prefix) > 0 {
+		internalPrefix = prefix
+	}
+	features = loadFeatureFlags()
+	corsAllowedOrigins = loadCORSOrigins()
+	limiter = loadRateLimiter()
+	poolConfig = loadPoolConfig()
+	log.Printf("Connection pool: %v\n", poolConfig)
+	initHistory()
+	initScanCount()
+	initCountScanTimeout()
+	if err := initClusters(); err != nil {
+		log.Fatal(err)
+	}
+	if dryRun {
+		log.Printf("Dry run mode: commands modifying data are logged and not sent to Valkey\n")
+	}
+	if rateStr := os.Getenv("VALKEY_CHAOS_RATE"); len(rateStr) > 0 {
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		switch {
+		case err != nil || rate < 0 || rate > 1:
+			log.Printf("Ignoring VALKEY_CHAOS_RATE=%v, expected a number between 0 and 1\n", rateStr)
+		case os.Getenv("VCAP_SERVICES") != "":
+			log.Printf("Ignoring VALKEY_CHAOS_RATE on Cloud Foundry\n")
+		case !chaosBuild:
+			log.Printf("Ignoring VALKEY_CHAOS_RATE, the app was built without -tags chaos\n")
+		default:
+			chaosRate = rate
+			log.Printf("Chaos mode: failing %v%% of the Valkey commands\n", rate*100)
+		}
+	}
 
 	port := "9090"
 	if port = os.Getenv("PORT"); len(port) == 0 {
@@ -254,12 +637,143 @@ func main() {
 
 	log.Printf("Public dir: %v\n", dir)
 
+	registerRoutes(dir)
+
+	logProtocolVersion()
+	if events := os.Getenv("VALKEY_KEYSPACE_EVENTS"); len(events) > 0 {
+		// nil stands for the single instance configuration
+		targets := []*ClusterConfig{nil}
+		if len(clusters) > 0 {
+			targets = targets[:0]
+			for i := range clusters {
+				targets = append(targets, &clusters[i])
+			}
+		}
+		for _, cluster := range targets {
+			configureKeyspaceEvents(cluster, events)
+			// the dry run would skip every write of the recording
+			if dryRun {
+				log.Printf("Dry run, the keyspace events are not recorded\n")
+				break
+			}
+			go subscribeKeyspaceEvents(cluster)
+			go watchEventTrigger(cluster)
+		}
+	}
+	go statsRefresher(durationFromEnv("STATS_REFRESH_INTERVAL", 30*time.Second))
+
+	if path := os.Getenv("PID_FILE"); len(path) > 0 {
+		writePIDFile(path)
+	}
+	startTime = time.Now()
+	err = serve(port)
+	if err != nil {
+		removePIDFile()
+		log.Fatal(err)
+	}
+}
+
+// register the handlers on http.DefaultServeMux, the static assets are served from dir/public
+func registerRoutes(dir string) {
 	fs := http.FileServer(http.Dir(path.Join(dir, "public")))
 	http.Handle("/public/", http.StripPrefix("/public/", fs))
-	http.HandleFunc("/", renderKeyValues)
-	http.HandleFunc("/key-values/new", newKeyValue)
-	http.HandleFunc("/key-values/create", createKeyValue)
-
-	log.Printf("Listening on :%v\n", port)
-	http.ListenAndServe(fmt.Sprintf(":%s", port), nil)
+	http.HandleFunc("GET /{$}", instrument("renderKeyValues", renderKeyValues))
+	http.HandleFunc("/", instrument("notFound", notFound))
+	http.HandleFunc("GET /key-values/new", instrument("newKeyValue", newKeyValue))
+	http.HandleFunc("POST /key-values/create", instrument("createKeyValue", createKeyValue))
+	http.HandleFunc("GET /key-values/random", instrument("randomKeyValue", randomKeyValue))
+	http.HandleFunc("GET /key-values/compare", instrument("renderCompare", renderCompare))
+	http.HandleFunc("GET /key-values/{key}", instrument("renderKeyDetails", renderKeyDetails))
+	http.HandleFunc("GET /key-values/{key}/hyperloglog", instrument("renderHyperLogLog", renderHyperLogLog))
+	http.HandleFunc("GET /key-values/{key}/geo", instrument("renderGeo", renderGeo))
+	http.HandleFunc("GET /key-values/{key}/hash", instrument("renderHash", renderHash))
+	http.HandleFunc("GET /key-values/{key}/edit", instrument("renderEditor", renderEditor))
+	http.HandleFunc("POST /key-values/{key}/hash/fields", instrument("addHashField", addHashField))
+	http.HandleFunc("POST /key-values/{key}/hash/fields/{field}", instrument("editHashField", editHashField))
+	http.HandleFunc("POST /key-values/{key}/hash/fields/{field}/delete", instrument("deleteHashField", deleteHashField))
+	http.HandleFunc("GET /key-values/{key}/list", instrument("renderList", renderList))
+	http.HandleFunc("POST /key-values/{key}/list/set", instrument("setListElement", setListElement))
+	http.HandleFunc("POST /key-values/{key}/list/pop", instrument("popListElement", popListElement))
+	http.HandleFunc("POST /key-values/{key}/list/insert", instrument("insertListElement", insertListElement))
+	http.HandleFunc("POST /key-values/{key}/list/trim", instrument("trimList", trimList))
+	http.HandleFunc("GET /key-values/{key}/set", instrument("renderSet", renderSet))
+	http.HandleFunc("GET /key-values/{key}/zset", instrument("renderZset", renderZset))
+	http.HandleFunc("GET /key-values/{key}/stream", instrument("renderStream", requireFeature(features.Streams, renderStream)))
+	http.HandleFunc("POST /api/v1/key-values", instrument("createKeyValueAPI", createKeyValueAPI))
+	http.HandleFunc("POST /api/v1/key-values/pfmerge", instrument("mergeHyperLogLogs", mergeHyperLogLogs))
+	http.HandleFunc("POST /api/v1/key-values/sets/union", instrument("setUnion", setOperation("union")))
+	http.HandleFunc("POST /api/v1/key-values/sets/intersect", instrument("setIntersect", setOperation("intersect")))
+	http.HandleFunc("POST /api/v1/key-values/sets/diff", instrument("setDiff", setOperation("diff")))
+	http.HandleFunc("GET /api/v1/key-values/{key}/set/random", instrument("getRandomSetMembers", getRandomSetMembers))
+	http.HandleFunc("POST /api/v1/key-values/{key}/set/pop", instrument("popSetMembers", popSetMembers))
+	http.HandleFunc("GET /api/v1/key-values/count", instrument("countKeys", countKeys))
+	http.HandleFunc("GET /api/v1/key-values/export", instrument("exportKeyValues", exportKeyValues))
+	http.HandleFunc("GET /api/v1/key-values/search", instrument("searchKeyValues", searchKeyValues))
+	http.HandleFunc("GET /api/v1/key-values/recent", instrument("getRecentKeys", getRecentKeys))
+	http.HandleFunc("GET /api/v1/key-values/compare", instrument("compareKeyValues", compareKeyValues))
+	http.HandleFunc("DELETE /api/v1/key-values/{key}", instrument("deleteKeyValue", deleteKeyValue))
+	http.HandleFunc("POST /api/v1/key-values/{key}/rename", instrument("renameKeyValue", renameKeyValue))
+	http.HandleFunc("GET /api/v1/key-values/{key}/value", instrument("getValue", getValue))
+	http.HandleFunc("GET /api/v1/key-values/{key}/qr", instrument("getValueQR", getValueQR))
+	http.HandleFunc("GET /api/v1/key-values/{key}/object", instrument("getObjectInfo", getObjectInfo))
+	http.HandleFunc("GET /api/v1/key-values/{key}/list/search", instrument("searchList", searchList))
+	http.HandleFunc("GET /api/v1/key-values/{key}/zset/range", instrument("getZsetRange", getZsetRange))
+	http.HandleFunc("POST /api/v1/key-values/{key}/zset/members/{member}/score", instrument("setZsetScore", setZsetScore))
+	http.HandleFunc("GET /api/v1/key-values/{key}/zset/min", instrument("peekZsetMin", peekZsetMin))
+	http.HandleFunc("DELETE /api/v1/key-values/{key}/zset/min", instrument("popZsetMin", popZsetMembers(false)))
+	http.HandleFunc("DELETE /api/v1/key-values/{key}/zset/max", instrument("popZsetMax", popZsetMembers(true)))
+	http.HandleFunc("GET /api/v1/key-values/{key}/stream/read", instrument("readStream", requireFeature(features.Streams, readStream)))
+	http.HandleFunc("GET /api/v1/key-values/{key}/stream/info", instrument("getStreamInfo", requireFeature(features.Streams, getStreamInfo)))
+	http.HandleFunc("DELETE /api/v1/key-values/{key}/stream/messages", instrument("deleteStreamEntries", requireFeature(features.Streams, deleteStreamEntries)))
+	http.HandleFunc("POST /api/v1/key-values/{key}/stream/trim", instrument("trimStream", requireFeature(features.Streams, trimStream)))
+	http.HandleFunc("GET /api/v1/key-values/{key}/stream/groups", instrument("getStreamGroups", requireFeature(features.Streams, getStreamGroups)))
+	http.HandleFunc("POST /api/v1/key-values/{key}/stream/groups", instrument("createStreamGroup", requireFeature(features.Streams, createStreamGroup)))
+	http.HandleFunc("DELETE /api/v1/key-values/{key}/stream/groups/{group}", instrument("deleteStreamGroup", requireFeature(features.Streams, deleteStreamGroup)))
+	http.HandleFunc("POST /api/v1/key-values/{key}/stream/groups/{group}/ack", instrument("ackStreamEntries", requireFeature(features.Streams, ackStreamEntries)))
+	http.HandleFunc("GET /api/v1/key-values/{key}/stream/groups/{group}/pending", instrument("getPendingEntries", requireFeature(features.Streams, getPendingEntries)))
+	http.HandleFunc("DELETE /api/v1/key-values/{key}/stream/groups/{group}/consumers/{consumer}", instrument("deleteStreamConsumer", requireFeature(features.Streams, deleteStreamConsumer)))
+	http.HandleFunc("POST /api/v1/key-values/{key}/move", instrument("moveKeyValue", moveKeyValue))
+	http.HandleFunc("POST /api/v1/key-values/{key}/persist", instrument("persistKeyValue", persistKeyValue))
+	http.HandleFunc("POST /api/v1/key-values/{key}/expire", instrument("expireKeyValue", expireKeyValue))
+	http.HandleFunc("GET /api/v1/key-values/{key}/ttl", instrument("getTTL", getTTL))
+	http.HandleFunc("PUT /api/v1/key-values/{key}/ttl", instrument("setTTL", setTTL))
+	http.HandleFunc("GET /api/v1/key-values/{key}/history", instrument("getHistory", getHistory))
+	http.HandleFunc("POST /api/v1/key-values/{key}/tags", instrument("addTags", requireFeature(features.Tags, addTags)))
+	http.HandleFunc("DELETE /api/v1/key-values/{key}/tags/{tag}", instrument("removeTag", requireFeature(features.Tags, removeTag)))
+	http.HandleFunc("POST /api/v1/key-values/{key}/bookmark", instrument("addBookmark", bookmarkKeyValue))
+	http.HandleFunc("DELETE /api/v1/key-values/{key}/bookmark", instrument("removeBookmark", bookmarkKeyValue))
+	http.HandleFunc("GET /api/v1/tags/{tag}", instrument("getTaggedKeys", requireFeature(features.Tags, getTaggedKeys)))
+	http.HandleFunc("GET /api/v1/clusters", instrument("getClusters", getClusters))
+	http.HandleFunc("POST /clusters/select", instrument("selectCluster", selectCluster))
+	http.HandleFunc("GET /admin/latency/history", instrument("latencyHistory", requireFeature(features.AdminPanel, latencyHistory)))
+	http.HandleFunc("GET /admin/latency/latest", instrument("latencyLatest", requireFeature(features.AdminPanel, latencyLatest)))
+	http.HandleFunc("POST /admin/latency/reset", instrument("latencyReset", requireFeature(features.AdminPanel, latencyReset)))
+	http.HandleFunc("GET /admin/encoding-report", instrument("encodingReport", requireFeature(features.AdminPanel, encodingReport)))
+	http.HandleFunc("GET /admin/memory/analysis", instrument("memoryAnalysis", requireFeature(features.AdminPanel, memoryAnalysis)))
+	http.HandleFunc("GET /admin/replication", instrument("replicationInfo", requireFeature(features.AdminPanel, replicationInfo)))
+	http.HandleFunc("GET /admin/cluster/info", instrument("clusterInfo", requireFeature(features.AdminPanel, clusterInfo)))
+	http.HandleFunc("GET /admin/cluster/nodes", instrument("clusterNodes", requireFeature(features.AdminPanel, clusterNodes)))
+	http.HandleFunc("POST /admin/bgsave", instrument("bgsave", requireFeature(features.AdminPanel, bgsave)))
+	http.HandleFunc("GET /admin/lastsave", instrument("lastsave", requireFeature(features.AdminPanel, lastsave)))
+	http.HandleFunc("GET /admin/object-help", instrument("objectHelp", requireFeature(features.AdminPanel, objectHelp)))
+	http.HandleFunc("GET /admin/commands", instrument("commandInfo", requireFeature(features.AdminPanel, commandInfo)))
+	http.HandleFunc("GET /admin/commands/count", instrument("commandCount", requireFeature(features.AdminPanel, commandCount)))
+	http.HandleFunc("GET /admin/commands/docs", instrument("commandDocs", requireFeature(features.AdminPanel, commandDocs)))
+	http.HandleFunc("GET /admin/commands/getkeys", instrument("commandGetKeys", requireFeature(features.AdminPanel, commandGetKeys)))
+	http.HandleFunc("GET /admin/env", instrument("renderEnv", requireFeature(features.AdminPanel, requireAdminToken(renderEnv))))
+	http.HandleFunc("GET /admin/acl", instrument("aclList", requireFeature(features.AdminPanel, requireAdminToken(aclList))))
+	http.HandleFunc("GET /admin/acl/whoami", instrument("aclWhoami", requireFeature(features.AdminPanel, requireAdminToken(aclWhoami))))
+	http.HandleFunc("GET /admin/acl/cat", instrument("aclCat", requireFeature(features.AdminPanel, requireAdminToken(aclCat))))
+	http.HandleFunc("POST /admin/load-generator", instrument("loadGenerator", requireFeature(features.AdminPanel, requireAdminToken(loadGenerator))))
+	http.HandleFunc("POST /admin/reset-connection", instrument("adminResetConnection", requireFeature(features.AdminPanel, requireAdminToken(adminResetConnection))))
+	http.HandleFunc("GET /admin/debug/object/{key}", instrument("debugObject", requireFeature(features.AdminPanel, requireAdminToken(debugObject))))
+	http.HandleFunc("POST /admin/debug/reload", instrument("debugReload", requireFeature(features.AdminPanel, requireAdminToken(debugReload))))
+	http.HandleFunc("POST /admin/debug/sleep", instrument("debugSleep", requireFeature(features.AdminPanel, requireAdminToken(debugSleep))))
+	http.HandleFunc("POST /admin/debug/change-repl-id", instrument("debugChangeReplID", requireFeature(features.AdminPanel, requireAdminToken(debugChangeReplID))))
+	http.HandleFunc("POST /admin/debug/quicklist-threshold", instrument("debugQuicklistThreshold", requireFeature(features.AdminPanel, requireAdminToken(debugQuicklistThreshold))))
+	http.HandleFunc("GET /stats", renderStats)
+	http.HandleFunc("GET /health", renderHealth)
+	http.HandleFunc("GET /version", renderVersion)
+	http.HandleFunc("GET /metrics", renderMetrics)
+	http.HandleFunc("GET /events/poll", instrument("pollEvents", pollEvents))
 }
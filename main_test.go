@@ -0,0 +1,526 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+	"github.com/valkey-io/valkey-go/mock"
+)
+
+// variables read by createCredentials, cleared by every case that does not set them
+var credentialsEnvVars = []string{
+	"VCAP_SERVICES", "VALKEY_ENV_PREFIX", "VALKEY_HOST", "VALKEY_PORT", "VALKEY_USERNAME", "VALKEY_PASSWORD",
+	"VALKEY_SERVICE_NAME", "VALKEY_SERVICE_INDEX", "VALKEY_SENTINEL_MASTER", "VALKEY_SENTINEL_USERNAME", "VALKEY_SENTINEL_PASSWORD",
+}
+
+func TestCreateCredentials(t *testing.T) {
+	caCert := "-----BEGIN CERTIFICATE-----"
+	localEnv := map[string]string{
+		"VALKEY_HOST":     "localhost",
+		"VALKEY_PORT":     "6379",
+		"VALKEY_USERNAME": "default",
+		"VALKEY_PASSWORD": "secret",
+	}
+	withEnv := func(env map[string]string, name, value string) map[string]string {
+		copied := make(map[string]string)
+		for k, v := range env {
+			copied[k] = v
+		}
+		copied[name] = value
+		return copied
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    ValkeyCredentials
+		wantErr bool
+	}{
+		{
+			name: "valid env vars",
+			env:  localEnv,
+			want: ValkeyCredentials{
+				Host:   "localhost",
+				Valkey: ValkeyDetails{Password: "secret", Port: 6379, Username: "default"},
+			},
+		},
+		{
+			name: "env vars with prefix",
+			env: map[string]string{
+				"VALKEY_ENV_PREFIX": "CACHE",
+				"CACHE_HOST":        "cache",
+				"CACHE_PORT":        "6380",
+				"CACHE_USERNAME":    "app",
+				"CACHE_PASSWORD":    "cache-secret",
+			},
+			want: ValkeyCredentials{
+				Host:   "cache",
+				Valkey: ValkeyDetails{Password: "cache-secret", Port: 6380, Username: "app"},
+			},
+		},
+		{
+			name: "sentinel env vars with prefix",
+			env: map[string]string{
+				"VALKEY_ENV_PREFIX":        "CACHE",
+				"CACHE_HOST":               "sentinel",
+				"CACHE_PORT":               "26379",
+				"CACHE_USERNAME":           "app",
+				"CACHE_PASSWORD":           "cache-secret",
+				"CACHE_SENTINEL_MASTER":    "mymaster",
+				"CACHE_SENTINEL_PASSWORD":  "sentinel-secret",
+				"VALKEY_SENTINEL_MASTER":   "other",
+				"VALKEY_SENTINEL_PASSWORD": "other-secret",
+			},
+			want: ValkeyCredentials{
+				Host:     "sentinel",
+				Valkey:   ValkeyDetails{Password: "cache-secret", Port: 26379, Username: "app"},
+				Sentinel: SentinelDetails{MasterSet: "mymaster", Password: "sentinel-secret"},
+			},
+		},
+		{
+			name:    "missing VALKEY_HOST",
+			env:     withEnv(localEnv, "VALKEY_HOST", ""),
+			wantErr: true,
+		},
+		{
+			name:    "missing VALKEY_PASSWORD",
+			env:     withEnv(localEnv, "VALKEY_PASSWORD", ""),
+			wantErr: true,
+		},
+		{
+			name:    "invalid VALKEY_PORT",
+			env:     withEnv(localEnv, "VALKEY_PORT", "port"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid VCAP JSON",
+			env:     map[string]string{"VCAP_SERVICES": `{"a9s-valkey":`},
+			wantErr: true,
+		},
+		{
+			name:    "empty service list",
+			env:     map[string]string{"VCAP_SERVICES": `{}`},
+			wantErr: true,
+		},
+		{
+			name: "valid VCAP with one instance",
+			env: map[string]string{"VCAP_SERVICES": `{"a9s-valkey80": [{"name": "valkey", "credentials": ` +
+				`{"host": "valkey.service", "valkey": {"password": "pw", "port": 6379, "username": "user"}}}]}`},
+			want: ValkeyCredentials{
+				Host:   "valkey.service",
+				Valkey: ValkeyDetails{Password: "pw", Port: 6379, Username: "user"},
+			},
+		},
+		{
+			name: "VCAP with TLS cert",
+			env: map[string]string{"VCAP_SERVICES": `{"a9s-valkey80": [{"name": "valkey", "credentials": ` +
+				`{"host": "valkey.service", "cacrt": "` + caCert + `", "valkey": {"password": "pw", "port": 6379, "username": "user"}}}]}`},
+			want: ValkeyCredentials{
+				Host:          "valkey.service",
+				CaCertificate: &caCert,
+				Valkey:        ValkeyDetails{Password: "pw", Port: 6379, Username: "user"},
+			},
+		},
+		{
+			name: "VCAP instance without host",
+			env: map[string]string{"VCAP_SERVICES": `{"a9s-valkey80": [{"name": "valkey", "credentials": ` +
+				`{"valkey": {"password": "pw", "port": 6379, "username": "user"}}}]}`},
+			wantErr: true,
+		},
+		{
+			name: "VCAP instance selected by name",
+			env: map[string]string{
+				"VALKEY_SERVICE_NAME": "second",
+				"VCAP_SERVICES": `{"a9s-valkey80": [` +
+					`{"name": "first", "credentials": {"host": "first.service", "port": 6379, "username": "a", "password": "a"}},` +
+					`{"name": "second", "credentials": {"host": "second.service", "port": 6379, "username": "b", "password": "b"}}]}`,
+			},
+			want: ValkeyCredentials{
+				Host:   "second.service",
+				Valkey: ValkeyDetails{Password: "b", Port: 6379, Username: "b"},
+			},
+		},
+		{
+			name: "unknown VALKEY_SERVICE_NAME",
+			env: map[string]string{
+				"VALKEY_SERVICE_NAME": "third",
+				"VCAP_SERVICES": `{"a9s-valkey80": [` +
+					`{"name": "first", "credentials": {"host": "first.service", "port": 6379, "username": "a", "password": "a"}}]}`,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range credentialsEnvVars {
+				t.Setenv(name, "")
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			got, err := createCredentials()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("createCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("createCredentials() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValkeyCredentialsString(t *testing.T) {
+	caCert := "-----BEGIN CERTIFICATE-----"
+	tests := []struct {
+		name        string
+		credentials ValkeyCredentials
+		want        string
+	}{
+		{
+			name: "standalone",
+			credentials: ValkeyCredentials{
+				Host:   "localhost",
+				Valkey: ValkeyDetails{Password: "secret", Port: 6379, Username: "default"},
+			},
+			want: "localhost:6379 as default (standalone)",
+		},
+		{
+			name: "sentinel with TLS",
+			credentials: ValkeyCredentials{
+				Host:          "sentinel.service",
+				CaCertificate: &caCert,
+				Valkey:        ValkeyDetails{Password: "secret", Port: 26379, Username: "app"},
+				Sentinel:      SentinelDetails{MasterSet: "mymaster", Password: "sentinel-secret"},
+			},
+			want: "sentinel.service:26379 as app (sentinel, master set mymaster, TLS)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmt.Sprintf("%v", tt.credentials); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderKeyValues(t *testing.T) {
+	loadTemplates()
+
+	tests := []struct {
+		name     string
+		query    string
+		store    map[string]string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "empty keyspace",
+			store:    map[string]string{},
+			excludes: []string{"greeting"},
+		},
+		{
+			name:     "strings",
+			store:    map[string]string{"greeting": "hello", "answer": "42"},
+			contains: []string{"greeting", "hello", "answer", "42"},
+		},
+		{
+			name:     "internal keys hidden",
+			store:    map[string]string{"greeting": "hello", internalKey("audit"): "secret"},
+			contains: []string{"greeting"},
+			excludes: []string{internalKey("audit")},
+		},
+		{
+			name:     "internal keys shown",
+			query:    "?internal=true",
+			store:    map[string]string{"greeting": "hello", internalKey("audit"): "secret"},
+			contains: []string{"greeting", internalKey("audit")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMockClient(t, tt.store)
+
+			w := httptest.NewRecorder()
+			renderKeyValues(w, httptest.NewRequest(http.MethodGet, "/"+tt.query, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %v, want %v", w.Code, http.StatusOK)
+			}
+			body := w.Body.String()
+			for _, s := range tt.contains {
+				if !strings.Contains(body, s) {
+					t.Errorf("body does not contain %q", s)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(body, s) {
+					t.Errorf("body contains %q", s)
+				}
+			}
+		})
+	}
+}
+
+// fails every call of command, the other commands reach the wrapped client
+type failingClient struct {
+	ValkeyClient
+	command string
+}
+
+func (c *failingClient) Do(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+	if commandName(cmd) == c.command {
+		return mock.ErrorResult(errors.New("connection reset"))
+	}
+	return c.ValkeyClient.Do(ctx, cmd)
+}
+
+func TestRenderKeyValuesErrors(t *testing.T) {
+	loadTemplates()
+
+	tests := []struct {
+		name      string
+		newClient func(r *http.Request) (ValkeyClient, error)
+		status    int
+	}{
+		{
+			name:      "connection failure",
+			newClient: func(r *http.Request) (ValkeyClient, error) { return nil, errors.New("dial tcp: connection refused") },
+			status:    http.StatusServiceUnavailable,
+		},
+		{
+			name: "scan failure",
+			newClient: func(r *http.Request) (ValkeyClient, error) {
+				return &failingClient{ValkeyClient: NewMockValkeyClient(), command: "SCAN"}, nil
+			},
+			status: http.StatusBadGateway,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := newClient
+			newClient = tt.newClient
+			t.Cleanup(func() { newClient = previous })
+
+			w := httptest.NewRecorder()
+			renderKeyValues(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if w.Code != tt.status {
+				t.Errorf("status = %v, want %v", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func BenchmarkRenderKeyValues_100(b *testing.B)   { benchmarkRenderKeyValues(b, 100) }
+func BenchmarkRenderKeyValues_1000(b *testing.B)  { benchmarkRenderKeyValues(b, 1000) }
+func BenchmarkRenderKeyValues_10000(b *testing.B) { benchmarkRenderKeyValues(b, 10000) }
+
+// render the index of a keyspace with n string keys, run with -benchmem for the allocations
+func benchmarkRenderKeyValues(b *testing.B, n int) {
+	loadTemplates()
+	store := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		store[fmt.Sprintf("key_%d", i)] = fmt.Sprintf("value_%d", i)
+	}
+	useMockClient(b, store)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		renderKeyValues(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			b.Fatalf("status = %v, want %v", w.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestCreateKeyValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		form      url.Values
+		status    int
+		wantKey   string
+		wantValue string
+	}{
+		{
+			name:      "string",
+			form:      url.Values{"key": {"greeting"}, "value": {"hello"}},
+			status:    http.StatusFound,
+			wantKey:   "greeting",
+			wantValue: "hello",
+		},
+		{
+			name:      "empty value",
+			form:      url.Values{"key": {"empty"}, "value": {""}},
+			status:    http.StatusFound,
+			wantKey:   "empty",
+			wantValue: "",
+		},
+		{
+			name:   "invalid replicas",
+			query:  "?replicas=many",
+			form:   url.Values{"key": {"greeting"}, "value": {"hello"}},
+			status: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadTemplates()
+			client := useMockClient(t, nil)
+
+			r := httptest.NewRequest(http.MethodPost, "/key-values/create"+tt.query, strings.NewReader(tt.form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			createKeyValue(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %v, want %v", w.Code, tt.status)
+			}
+			if len(tt.wantKey) < 1 {
+				if len(client.store) > 0 {
+					t.Errorf("store = %v, want no keys", client.store)
+				}
+				return
+			}
+			if value, ok := client.store[tt.wantKey]; !ok || value != tt.wantValue {
+				t.Errorf("store[%q] = %q, %v, want %q", tt.wantKey, value, ok, tt.wantValue)
+			}
+		})
+	}
+}
+
+// concurrent form posts, every iteration creates its own bench_key_<n>
+// the mock answers in memory, the numbers are the overhead of the handler, see integration_test.go for Valkey
+func BenchmarkCreateKeyValue(b *testing.B) {
+	loadTemplates()
+	client := useMockClient(b, nil)
+	var next atomic.Int64
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			form := url.Values{"key": {fmt.Sprintf("bench_key_%d", next.Add(1))}, "value": {"bench"}}
+			r := httptest.NewRequest(http.MethodPost, "/key-values/create", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			createKeyValue(w, r)
+			if w.Code != http.StatusFound {
+				b.Errorf("status = %v, want %v", w.Code, http.StatusFound)
+			}
+		}
+	})
+	b.StopTimer()
+
+	if deleted := deleteMatching(b, client, "bench_key_*"); deleted != next.Load() {
+		b.Errorf("deleted %v keys, want %v", deleted, next.Load())
+	}
+}
+
+// SCAN for the keys matching pattern and DEL them page by page
+func deleteMatching(tb testing.TB, client ValkeyClient, pattern string) int64 {
+	tb.Helper()
+	ctx := context.Background()
+	var deleted int64
+	var cursor uint64
+	for {
+		entry, err := client.Do(ctx, client.B().Scan().Cursor(cursor).Match(pattern).Count(scanCount).Build()).AsScanEntry()
+		if err != nil {
+			tb.Fatalf("SCAN %v: %v", pattern, err)
+		}
+		if len(entry.Elements) > 0 {
+			n, err := client.Do(ctx, client.B().Del().Key(entry.Elements...).Build()).AsInt64()
+			if err != nil {
+				tb.Fatalf("DEL: %v", err)
+			}
+			deleted += n
+		}
+		cursor = entry.Cursor
+		if cursor == 0 {
+			return deleted
+		}
+	}
+}
+
+func TestCreateKeyValueAPI(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		body   string
+		status int
+	}{
+		{name: "created", body: `{"key":"greeting","value":"hello"}`, status: http.StatusCreated},
+		{name: "empty key", body: `{"key":"","value":"hello"}`, status: http.StatusBadRequest},
+		{name: "invalid json", body: `{"key":`, status: http.StatusBadRequest},
+		// the mock has no replicas, WAIT returns 0 after the timeout
+		{name: "wait timed out", query: "?replicas=1&timeout_ms=50", body: `{"key":"greeting","value":"hello"}`, status: http.StatusAccepted},
+		{name: "wait without timeout", query: "?replicas=1&timeout_ms=0", body: `{"key":"greeting","value":"hello"}`, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := useMockClient(t, nil)
+			// the WAIT outlasts the command timeout, the request deadline has to cover it
+			previous := valkeyCmdTimeout
+			valkeyCmdTimeout = 10 * time.Millisecond
+			t.Cleanup(func() { valkeyCmdTimeout = previous })
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/key-values"+tt.query, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			createKeyValueAPI(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %v, want %v, body %v", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status == http.StatusCreated && client.store["greeting"] != "hello" {
+				t.Errorf("store = %v, want greeting=hello", client.store)
+			}
+			if tt.status == http.StatusAccepted && !strings.Contains(w.Body.String(), `"replicated_to":0`) {
+				t.Errorf("body = %v, want replicated_to 0", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeleteKeyValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		status int
+	}{
+		{name: "existing", key: "greeting", status: http.StatusOK},
+		{name: "missing", key: "nope", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := useMockClient(t, map[string]string{"greeting": "hello"})
+
+			r := httptest.NewRequest(http.MethodDelete, "/api/v1/key-values/"+tt.key, nil)
+			r.SetPathValue("key", tt.key)
+			w := httptest.NewRecorder()
+			deleteKeyValue(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %v, want %v", w.Code, tt.status)
+			}
+			if _, ok := client.store[tt.key]; ok {
+				t.Errorf("key %v still stored", tt.key)
+			}
+		})
+	}
+}
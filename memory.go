@@ -0,0 +1,126 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// keys analyzed by /admin/memory/analysis unless ?sample= is given
+const (
+	defaultMemorySample = 1000
+	maxMemorySample     = 100000
+)
+
+// number of the largest keys in the analysis
+const memoryTopKeys = 10
+
+type TypeMemory struct {
+	Count int64 `json:"count"`
+	Bytes int64 `json:"bytes"`
+}
+
+type KeyMemory struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	Bytes int64  `json:"bytes"`
+}
+
+// MEMORY USAGE per key type of up to ?sample= scanned keys, with the largest keys
+func memoryAnalysis(w http.ResponseWriter, r *http.Request) {
+	sample := defaultMemorySample
+	if sampleStr := r.URL.Query().Get("sample"); len(sampleStr) > 0 {
+		var err error
+		sample, err = strconv.Atoi(sampleStr)
+		if err != nil || sample < 1 {
+			writeJSONError(w, http.StatusBadRequest, "query parameter sample must be a positive integer")
+			return
+		}
+		sample = min(sample, maxMemorySample)
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	byType := make(map[string]*TypeMemory)
+	keys := make([]KeyMemory, 0)
+	var total int64
+	var scanned int
+	var cursor uint64
+	// keys left out because of the sample size
+	truncated := false
+	for {
+		entry, err := client.Do(ctx, client.B().Scan().Cursor(cursor).Count(scanCount).Build()).AsScanEntry()
+		if err != nil {
+			log.Printf("Failed to scan keys, err = %v\n", valkeyErr(err))
+			writeJSONError(w, http.StatusBadGateway, "failed to scan keys")
+			return
+		}
+		page := make([]string, 0, len(entry.Elements))
+		for _, key := range entry.Elements {
+			if isInternalKey(key) {
+				continue
+			}
+			if scanned >= sample {
+				truncated = true
+				break
+			}
+			page = append(page, key)
+			scanned++
+		}
+		cmds := make(valkey.Commands, 0, 2*len(page))
+		for _, key := range page {
+			cmds = append(cmds, client.B().Type().Key(key).Build(), client.B().MemoryUsage().Key(key).Build())
+		}
+		resps := client.DoMulti(ctx, cmds...)
+		for i, key := range page {
+			keyType, err := resps[2*i].ToString()
+			if err != nil || keyType == "none" {
+				// expired since the scan
+				continue
+			}
+			bytes, err := resps[2*i+1].AsInt64()
+			if err != nil {
+				log.Printf("Failed to fetch memory usage of key %v, err = %v\n", key, valkeyErr(err))
+				continue
+			}
+			if byType[keyType] == nil {
+				byType[keyType] = &TypeMemory{}
+			}
+			byType[keyType].Count++
+			byType[keyType].Bytes += bytes
+			total += bytes
+			keys = append(keys, KeyMemory{Key: key, Type: keyType, Bytes: bytes})
+		}
+
+		cursor = entry.Cursor
+		if cursor == 0 || truncated {
+			break
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Bytes > keys[j].Bytes })
+	if len(keys) > memoryTopKeys {
+		keys = keys[:memoryTopKeys]
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"by_type":      byType,
+		"total_bytes":  total,
+		"largest_keys": keys,
+		"sampled_keys": scanned,
+		// the whole keyspace was analyzed
+		"complete": cursor == 0 && !truncated,
+	})
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// upper bounds of the latency histogram in seconds
+var commandBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+// commands with subcommands, e.g. CLIENT INFO, are labeled with both words
+var containerCommands = map[string]bool{
+	"ACL": true, "CLIENT": true, "CLUSTER": true, "COMMAND": true, "CONFIG": true, "FUNCTION": true,
+	"LATENCY": true, "MEMORY": true, "OBJECT": true, "SCRIPT": true, "XGROUP": true, "XINFO": true,
+}
+
+// latency histogram and error count of a single command
+type commandMetrics struct {
+	buckets []int64
+	count   int64
+	sum     float64
+	errors  int64
+}
+
+// per command metrics, see /metrics
+var (
+	commandMetricsMu sync.Mutex
+	commandStats     = make(map[string]*commandMetrics)
+)
+
+// label of a command, the command is recycled by Do and has to be named before
+func commandName(cmd valkey.Completed) string {
+	args := cmd.Commands()
+	if len(args) < 1 {
+		return "UNKNOWN"
+	}
+	name := strings.ToUpper(args[0])
+	if containerCommands[name] && len(args) > 1 {
+		name += " " + strings.ToUpper(args[1])
+	}
+	return name
+}
+
+func observeCommand(name string, duration time.Duration, err error) {
+	commandMetricsMu.Lock()
+	defer commandMetricsMu.Unlock()
+
+	metrics, ok := commandStats[name]
+	if !ok {
+		metrics = &commandMetrics{buckets: make([]int64, len(commandBuckets))}
+		commandStats[name] = metrics
+	}
+	seconds := duration.Seconds()
+	for i, bound := range commandBuckets {
+		if seconds <= bound {
+			metrics.buckets[i]++
+		}
+	}
+	metrics.count++
+	metrics.sum += seconds
+	// nil replies are answers, not failures
+	if err != nil && !valkey.IsValkeyNil(err) {
+		metrics.errors++
+	}
+}
+
+// Do with a latency observation labeled with the command name
+func doWithMetrics(ctx context.Context, client ValkeyClient, cmd valkey.Completed) valkey.ValkeyResult {
+	name := commandName(cmd)
+	start := time.Now()
+	resp := client.Do(ctx, cmd)
+	observeCommand(name, time.Since(start), resp.Error())
+	return resp
+}
+
+// blocking commands that found all connections of the pool in use, see poolSlots.acquire
+// the server side connection figures are refreshed by statsRefresher
+var poolWaits atomic.Int64
+
+// records the metrics of every command sent to Valkey
+type metricsClient struct {
+	ValkeyClient
+}
+
+func newMetricsClient(client ValkeyClient) metricsClient {
+	return metricsClient{client}
+}
+
+func (c metricsClient) Do(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+	return doWithMetrics(ctx, c.ValkeyClient, cmd)
+}
+
+// the commands of a pipeline are observed with the duration of the whole round trip
+func (c metricsClient) DoMulti(ctx context.Context, multi ...valkey.Completed) []valkey.ValkeyResult {
+	names := make([]string, len(multi))
+	for i, cmd := range multi {
+		names[i] = commandName(cmd)
+	}
+	start := time.Now()
+	resps := c.ValkeyClient.DoMulti(ctx, multi...)
+	duration := time.Since(start)
+	for i, resp := range resps {
+		observeCommand(names[i], duration, resp.Error())
+	}
+	return resps
+}
+
+// Prometheus text format
+func renderMetrics(w http.ResponseWriter, r *http.Request) {
+	commandMetricsMu.Lock()
+	names := make([]string, 0, len(commandStats))
+	for name := range commandStats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out strings.Builder
+	out.WriteString("# HELP valkey_command_duration_seconds Latency of the commands sent to Valkey.\n")
+	out.WriteString("# TYPE valkey_command_duration_seconds histogram\n")
+	for _, name := range names {
+		metrics := commandStats[name]
+		for i, bound := range commandBuckets {
+			fmt.Fprintf(&out, "valkey_command_duration_seconds_bucket{command=%q,le=\"%v\"} %v\n", name, bound, metrics.buckets[i])
+		}
+		fmt.Fprintf(&out, "valkey_command_duration_seconds_bucket{command=%q,le=\"+Inf\"} %v\n", name, metrics.count)
+		fmt.Fprintf(&out, "valkey_command_duration_seconds_sum{command=%q} %v\n", name, metrics.sum)
+		fmt.Fprintf(&out, "valkey_command_duration_seconds_count{command=%q} %v\n", name, metrics.count)
+	}
+	out.WriteString("# HELP valkey_command_errors_total Commands answered with an error, nil replies excluded.\n")
+	out.WriteString("# TYPE valkey_command_errors_total counter\n")
+	for _, name := range names {
+		fmt.Fprintf(&out, "valkey_command_errors_total{command=%q} %v\n", name, commandStats[name].errors)
+	}
+	commandMetricsMu.Unlock()
+
+	out.WriteString("# HELP valkey_pool_total_conns Client connections of the server, connected_clients of INFO clients.\n")
+	out.WriteString("# TYPE valkey_pool_total_conns gauge\n")
+	fmt.Fprintf(&out, "valkey_pool_total_conns %v\n", connectedClients.Load())
+	out.WriteString("# HELP valkey_pool_active_conns Connections of the pools for blocking commands in use, over all clusters.\n")
+	out.WriteString("# TYPE valkey_pool_active_conns gauge\n")
+	fmt.Fprintf(&out, "valkey_pool_active_conns %v\n", poolConnsInUse())
+	// the server figures include the connections of other clients of the server
+	out.WriteString("# HELP valkey_pool_idle_conns Client connections of the server not blocked in a command, connected_clients minus blocked_clients of INFO clients.\n")
+	out.WriteString("# TYPE valkey_pool_idle_conns gauge\n")
+	fmt.Fprintf(&out, "valkey_pool_idle_conns %v\n", max(connectedClients.Load()-blockedClients.Load(), 0))
+	out.WriteString("# HELP valkey_pool_wait_total Blocking commands that waited for a connection of a full pool.\n")
+	out.WriteString("# TYPE valkey_pool_wait_total counter\n")
+	fmt.Fprintf(&out, "valkey_pool_wait_total %v\n", poolWaits.Load())
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(out.String()))
+}
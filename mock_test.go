@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+	"github.com/valkey-io/valkey-go/mock"
+)
+
+// in-memory ValkeyClient for exercising the handlers without a Valkey server
+// only the string commands used by the handlers are understood, see useMockClient
+type MockValkeyClient struct {
+	mu      sync.Mutex
+	store   map[string]string
+	builder valkey.Builder
+	// commands per dedicated connection, see Dedicated
+	dedicated [][]string
+}
+
+func NewMockValkeyClient() *MockValkeyClient {
+	return &MockValkeyClient{
+		store: make(map[string]string),
+		// the builder of the mock package allows keys of different slots in one command
+		builder: mock.NewClient(nil).B(),
+	}
+}
+
+func (c *MockValkeyClient) B() valkey.Builder {
+	return c.builder
+}
+
+// the store survives Close, so the handlers can share the mock like a real server
+func (c *MockValkeyClient) Close() {}
+
+func (c *MockValkeyClient) DoMulti(ctx context.Context, multi ...valkey.Completed) []valkey.ValkeyResult {
+	resps := make([]valkey.ValkeyResult, 0, len(multi))
+	for _, cmd := range multi {
+		resps = append(resps, c.Do(ctx, cmd))
+	}
+	return resps
+}
+
+// every call gets a new connection that records its commands
+func (c *MockValkeyClient) Dedicated(fn func(valkey.DedicatedClient) error) error {
+	c.mu.Lock()
+	c.dedicated = append(c.dedicated, nil)
+	conn := &mockDedicatedClient{client: c, index: len(c.dedicated) - 1}
+	c.mu.Unlock()
+	return fn(conn)
+}
+
+// the mock publishes nothing, the subscription only ends with the context
+func (c *MockValkeyClient) Receive(ctx context.Context, subscribe valkey.Completed, fn func(valkey.PubSubMessage)) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (c *MockValkeyClient) Do(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+	if err := ctx.Err(); err != nil {
+		return mock.ErrorResult(err)
+	}
+
+	args := cmd.Commands()
+	if strings.ToUpper(args[0]) == "WAIT" {
+		return c.wait(ctx, args[2])
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		value, ok := c.store[args[1]]
+		if !ok {
+			return mock.Result(mock.ValkeyNil())
+		}
+		return mock.Result(mock.ValkeyBlobString(value))
+	case "SET":
+		c.store[args[1]] = args[2]
+		return mock.Result(mock.ValkeyString("OK"))
+	case "DEL", "UNLINK":
+		var deleted int64
+		for _, key := range args[1:] {
+			if _, ok := c.store[key]; ok {
+				delete(c.store, key)
+				deleted++
+			}
+		}
+		return mock.Result(mock.ValkeyInt64(deleted))
+	case "EXISTS":
+		var found int64
+		for _, key := range args[1:] {
+			if _, ok := c.store[key]; ok {
+				found++
+			}
+		}
+		return mock.Result(mock.ValkeyInt64(found))
+	case "TYPE":
+		if _, ok := c.store[args[1]]; ok {
+			return mock.Result(mock.ValkeyString("string"))
+		}
+		return mock.Result(mock.ValkeyString("none"))
+	case "SMEMBERS":
+		// sets are not stored, a missing key is an empty set
+		if _, ok := c.store[args[1]]; ok {
+			return mock.Result(mock.ValkeyError("WRONGTYPE Operation against a key holding the wrong kind of value"))
+		}
+		return mock.Result(mock.ValkeyArray())
+	case "PING":
+		return mock.Result(mock.ValkeyString("PONG"))
+	case "RESET":
+		return mock.Result(mock.ValkeyString("RESET"))
+	case "HELLO", "AUTH", "SELECT":
+		return mock.Result(mock.ValkeyString("OK"))
+	case "DBSIZE":
+		return mock.Result(mock.ValkeyInt64(int64(len(c.store))))
+	case "SCAN":
+		return c.scan(args[2:])
+	}
+	return mock.Result(mock.ValkeyError("ERR unknown command '" + args[0] + "'"))
+}
+
+// dedicated connection of MockValkeyClient, the commands go to the store of the client
+type mockDedicatedClient struct {
+	valkey.DedicatedClient
+	client *MockValkeyClient
+	index  int
+}
+
+func (c *mockDedicatedClient) B() valkey.Builder {
+	return c.client.B()
+}
+
+func (c *mockDedicatedClient) Do(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+	c.client.mu.Lock()
+	c.client.dedicated[c.index] = append(c.client.dedicated[c.index], strings.Join(cmd.Commands(), " "))
+	c.client.mu.Unlock()
+	return c.client.Do(ctx, cmd)
+}
+
+func (c *mockDedicatedClient) DoMulti(ctx context.Context, multi ...valkey.Completed) []valkey.ValkeyResult {
+	resps := make([]valkey.ValkeyResult, 0, len(multi))
+	for _, cmd := range multi {
+		resps = append(resps, c.Do(ctx, cmd))
+	}
+	return resps
+}
+
+// no replica acknowledges, WAIT answers 0 once the timeout in milliseconds passed
+func (c *MockValkeyClient) wait(ctx context.Context, timeoutMs string) valkey.ValkeyResult {
+	ms, err := strconv.ParseInt(timeoutMs, 10, 64)
+	if err != nil {
+		return mock.Result(mock.ValkeyError("ERR timeout is not an integer or out of range"))
+	}
+	select {
+	case <-time.After(time.Duration(ms) * time.Millisecond):
+		return mock.Result(mock.ValkeyInt64(0))
+	case <-ctx.Done():
+		return mock.ErrorResult(ctx.Err())
+	}
+}
+
+// answer a SCAN with all matching keys in a single page
+func (c *MockValkeyClient) scan(options []string) valkey.ValkeyResult {
+	pattern := "*"
+	for i := 0; i+1 < len(options); i += 2 {
+		if strings.ToUpper(options[i]) == "MATCH" {
+			pattern = options[i+1]
+		}
+	}
+
+	keys := make([]string, 0, len(c.store))
+	for key := range c.store {
+		if ok, _ := path.Match(pattern, key); ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	elements := make([]valkey.ValkeyMessage, 0, len(keys))
+	for _, key := range keys {
+		elements = append(elements, mock.ValkeyBlobString(key))
+	}
+	return mock.Result(mock.ValkeyArray(
+		mock.ValkeyBlobString("0"),
+		mock.ValkeyArray(elements...),
+	))
+}
+
+// serve the handlers from a new mock until the end of the test
+func useMockClient(tb testing.TB, store map[string]string) *MockValkeyClient {
+	tb.Helper()
+	client := NewMockValkeyClient()
+	for key, value := range store {
+		client.store[key] = value
+	}
+	previous := newClient
+	newClient = func(r *http.Request) (ValkeyClient, error) { return client, nil }
+	tb.Cleanup(func() { newClient = previous })
+	return client
+}
+
+// parse the templates once for all tests, main does it at startup
+var templatesOnce sync.Once
+
+func loadTemplates() {
+	templatesOnce.Do(initTemplates)
+}
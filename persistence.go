@@ -0,0 +1,61 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// start an RDB snapshot in the background
+func bgsave(w http.ResponseWriter, r *http.Request) {
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	status, err := replyString(client.Do(ctx, client.B().Bgsave().Build()))
+	if err != nil && strings.Contains(err.Error(), "already in progress") {
+		writeJSONError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to start background save, err = %v\n", valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to start background save")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": status})
+}
+
+// time of the last successful save
+func lastsave(w http.ResponseWriter, r *http.Request) {
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	unix, err := client.Do(ctx, client.B().Lastsave().Build()).AsInt64()
+	if err != nil {
+		log.Printf("Failed to fetch last save, err = %v\n", valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to fetch last save")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"unix_timestamp": unix,
+		"human":          time.Unix(unix, 0).UTC().Format(time.RFC3339),
+	})
+}
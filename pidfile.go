@@ -0,0 +1,30 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// path of the PID file, see PID_FILE
+var pidFile string
+
+// write the process ID for deployment scripts and monitoring agents
+// the app also runs without the file
+func writePIDFile(path string) {
+	err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644)
+	if err != nil {
+		log.Printf("Warning: failed to write PID file %v: %v\n", path, err)
+		return
+	}
+	pidFile = path
+}
+
+func removePIDFile() {
+	if len(pidFile) < 1 {
+		return
+	}
+	if err := os.Remove(pidFile); err != nil {
+		log.Printf("Failed to remove PID file %v: %v\n", pidFile, err)
+	}
+}
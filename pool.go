@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// interval in which idle connections above MinIdle are closed
+const poolCleanupInterval = time.Minute
+
+// pool of the connections for blocking commands, pipelined commands share PipelineMultiplex connections
+// every cluster has one pool, shared by all requests, see sharedClient
+type PoolConfig struct {
+	// most connections of the pool
+	Size int
+	// idle connections the cleanup keeps open, BlockingPoolMinSize of valkey-go
+	// a minimum and not a cap, idle connections above it stay open until the next cleanup
+	MinIdle int
+	// longest wait of a blocking command for a connection of a full pool
+	Timeout time.Duration
+}
+
+// read VALKEY_POOL_SIZE, VALKEY_MAX_IDLE_CONNS and VALKEY_POOL_TIMEOUT
+func loadPoolConfig() PoolConfig {
+	config := PoolConfig{
+		Size:    intFromEnv("VALKEY_POOL_SIZE", valkey.DefaultPoolSize),
+		MinIdle: intFromEnv("VALKEY_MAX_IDLE_CONNS", 10),
+		Timeout: durationFromEnv("VALKEY_POOL_TIMEOUT", 5*time.Second),
+	}
+	if config.Size < 1 {
+		log.Printf("Invalid VALKEY_POOL_SIZE=%v, using %v\n", config.Size, valkey.DefaultPoolSize)
+		config.Size = valkey.DefaultPoolSize
+	}
+	if config.MinIdle < 0 {
+		log.Printf("Invalid VALKEY_MAX_IDLE_CONNS=%v, using 0\n", config.MinIdle)
+		config.MinIdle = 0
+	}
+	if config.MinIdle > config.Size {
+		config.MinIdle = config.Size
+	}
+	// a blocking command of a full pool would fail without waiting
+	if config.Timeout <= 0 {
+		log.Printf("Invalid VALKEY_POOL_TIMEOUT=%v, using %v\n", config.Timeout, 5*time.Second)
+		config.Timeout = 5 * time.Second
+	}
+	return config
+}
+
+// populated at startup, see loadPoolConfig
+var poolConfig = PoolConfig{Size: valkey.DefaultPoolSize, MinIdle: 10, Timeout: 5 * time.Second}
+
+func (c PoolConfig) apply(options *valkey.ClientOption) {
+	options.BlockingPoolSize = c.Size
+	options.BlockingPoolMinSize = c.MinIdle
+	options.BlockingPoolCleanup = poolCleanupInterval
+}
+
+func (c PoolConfig) String() string {
+	return fmt.Sprintf("size %v, min idle %v, timeout %v", c.Size, c.MinIdle, c.Timeout)
+}
+
+var errPoolTimeout = errors.New("timed out waiting for a connection of the pool")
+
+// valkey.Client shared by all requests to one cluster, Close keeps the connections for the next request
+type sharedClient struct {
+	valkey.Client
+}
+
+func newSharedClient(client valkey.Client) *sharedClient {
+	return &sharedClient{Client: client}
+}
+
+func (c *sharedClient) Close() {}
+
+// connections of the blocking pool of one cluster taken by commands
+// the pool of valkey-go waits for a returned connection without a timeout, so the callers of
+// blocking commands take one of Size slots first and give up after Timeout, see acquireBlockingConn
+type poolSlots struct {
+	slots   chan struct{}
+	timeout time.Duration
+}
+
+// slots per cluster, keyed by the cluster name like sharedClients
+var (
+	poolSlotsMu        sync.Mutex
+	poolSlotsByCluster = make(map[string]*poolSlots)
+)
+
+func clusterPoolSlots(cluster *ClusterConfig) *poolSlots {
+	name := ""
+	if cluster != nil {
+		name = cluster.Name
+	}
+	poolSlotsMu.Lock()
+	defer poolSlotsMu.Unlock()
+	slots, ok := poolSlotsByCluster[name]
+	if !ok {
+		slots = newPoolSlots(poolConfig)
+		poolSlotsByCluster[name] = slots
+	}
+	return slots
+}
+
+func newPoolSlots(config PoolConfig) *poolSlots {
+	return &poolSlots{slots: make(chan struct{}, config.Size), timeout: config.Timeout}
+}
+
+// take a connection of the blocking pool of the cluster before sending a blocking command,
+// e.g. WAIT, XREAD with BLOCK or a dedicated connection, and release it once the command returned
+func acquireBlockingConn(ctx context.Context, cluster *ClusterConfig) (release func(), err error) {
+	slots := clusterPoolSlots(cluster)
+	if err := slots.acquire(ctx); err != nil {
+		return nil, err
+	}
+	return slots.release, nil
+}
+
+// take a slot, waiting up to the pool timeout if all connections are in use
+func (s *poolSlots) acquire(ctx context.Context) error {
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	poolWaits.Add(1)
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("%w after %v, all %v connections are in use", errPoolTimeout, s.timeout, cap(s.slots))
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *poolSlots) release() {
+	<-s.slots
+}
+
+// connections of the blocking pools taken by commands, over all clusters
+func poolConnsInUse() int {
+	poolSlotsMu.Lock()
+	defer poolSlotsMu.Unlock()
+	inUse := 0
+	for _, slots := range poolSlotsByCluster {
+		inUse += len(slots.slots)
+	}
+	return inUse
+}
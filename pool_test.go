@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPoolSlotsTimeout(t *testing.T) {
+	slots := newPoolSlots(PoolConfig{Size: 1, Timeout: 20 * time.Millisecond})
+	if err := slots.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire of an empty pool error = %v", err)
+	}
+
+	waits := poolWaits.Load()
+	if err := slots.acquire(context.Background()); !errors.Is(err, errPoolTimeout) {
+		t.Errorf("acquire of a full pool error = %v, want %v", err, errPoolTimeout)
+	}
+	if got := poolWaits.Load() - waits; got != 1 {
+		t.Errorf("pool waits = %v, want 1", got)
+	}
+	// the request ends before the pool timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := slots.acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("acquire with a canceled request error = %v, want %v", err, context.Canceled)
+	}
+
+	slots.release()
+	if err := slots.acquire(context.Background()); err != nil {
+		t.Errorf("acquire after the release error = %v", err)
+	}
+}
+
+func TestLoadPoolConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		minIdle int
+		timeout time.Duration
+	}{
+		{name: "defaults", minIdle: 10, timeout: 5 * time.Second},
+		{name: "configured", env: map[string]string{"VALKEY_MAX_IDLE_CONNS": "2", "VALKEY_POOL_TIMEOUT": "1s"}, minIdle: 2, timeout: time.Second},
+		{name: "negative min idle", env: map[string]string{"VALKEY_MAX_IDLE_CONNS": "-1"}, minIdle: 10, timeout: 5 * time.Second},
+		{name: "zero timeout", env: map[string]string{"VALKEY_POOL_TIMEOUT": "0"}, minIdle: 10, timeout: 5 * time.Second},
+		{name: "negative timeout", env: map[string]string{"VALKEY_POOL_TIMEOUT": "-1s"}, minIdle: 10, timeout: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"VALKEY_POOL_SIZE", "VALKEY_MAX_IDLE_CONNS", "VALKEY_POOL_TIMEOUT"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			config := loadPoolConfig()
+			if config.MinIdle != tt.minIdle || config.Timeout != tt.timeout {
+				t.Errorf("loadPoolConfig() = %v, want min idle %v, timeout %v", config, tt.minIdle, tt.timeout)
+			}
+		})
+	}
+}
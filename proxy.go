@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// take the client address from the proxy headers, see TRUST_PROXY
+// in Cloud Foundry the TCP peer is always the router
+var trustProxy bool
+
+// TRUST_PROXY, on by default in Cloud Foundry
+// without it the rate limit would put all clients into the bucket of the router
+func loadTrustProxy() bool {
+	if value := os.Getenv("TRUST_PROXY"); len(value) > 0 {
+		return value == "true"
+	}
+	return len(os.Getenv("VCAP_APPLICATION")) > 0
+}
+
+// address of the client that sent the request, without the port
+// the headers are only read behind a trusted proxy, otherwise any client could spoof them
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); len(forwarded) > 0 {
+			first, _, _ := strings.Cut(forwarded, ",")
+			if ip := strings.TrimSpace(first); len(ip) > 0 {
+				return ip
+			}
+		}
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); len(ip) > 0 {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/valkey-io/valkey-go"
+)
+
+// bounds of ?size=, in pixels
+const (
+	qrDefaultSize = 256
+	qrMaxSize     = 2048
+)
+
+// QR code PNG of a string value, e.g. to scan a stored URL with a phone
+func getValueQR(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	size := qrDefaultSize
+	if sizeStr := r.URL.Query().Get("size"); len(sizeStr) > 0 {
+		var err error
+		size, err = strconv.Atoi(sizeStr)
+		if err != nil || size < 1 || size > qrMaxSize {
+			renderError(w, r, http.StatusBadRequest, fmt.Sprintf("size must be between 1 and %v", qrMaxSize))
+			return
+		}
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		renderError(w, r, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	value, err := client.Do(ctx, client.B().Get().Key(key).Build()).ToString()
+	if valkey.IsValkeyNil(err) {
+		renderError(w, r, http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch value for key %v, err = %v\n", key, valkeyErr(err))
+		renderError(w, r, http.StatusBadGateway, fmt.Sprintf("failed to fetch value for key %v", key))
+		return
+	}
+
+	png, err := qrcode.Encode(value, qrcode.Medium, size)
+	if err != nil {
+		// values above about 2KB do not fit into a QR code
+		renderError(w, r, http.StatusUnprocessableEntity, fmt.Sprintf("failed to encode value of key %v: %v", key, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(png)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// number of elements in the summary of hashes, lists, sets and sorted sets
+const randomSummarySize = 10
+
+// sample of a random key, Value is set for strings, Length and Elements for the other types
+type RandomKeyValue struct {
+	Key    string  `json:"key"`
+	Type   string  `json:"type"`
+	Value  *string `json:"value,omitempty"`
+	Length *int64  `json:"length,omitempty"`
+	// field names for hashes
+	Elements []string `json:"elements,omitempty"`
+}
+
+// RANDOMKEY with its value as JSON, browsers are redirected to the detail page
+func randomKeyValue(w http.ResponseWriter, r *http.Request) {
+	asJSON := strings.Contains(r.Header.Get("Accept"), "application/json")
+	fail := func(status int, message string) {
+		if asJSON {
+			writeJSONError(w, status, message)
+		} else {
+			renderError(w, r, status, message)
+		}
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		fail(http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	key, err := client.Do(ctx, client.B().Randomkey().Build()).ToString()
+	if valkey.IsValkeyNil(err) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch a random key, err = %v\n", valkeyErr(err))
+		fail(http.StatusBadGateway, "failed to fetch a random key")
+		return
+	}
+
+	if !asJSON {
+		http.Redirect(w, r, "/key-values/"+url.PathEscape(key), http.StatusFound)
+		return
+	}
+
+	sample := RandomKeyValue{Key: key}
+	if sample.Type, err = client.Do(ctx, client.B().Type().Key(key).Build()).ToString(); err != nil {
+		log.Printf("Failed to fetch type of key %v, err = %v\n", key, valkeyErr(err))
+		fail(http.StatusBadGateway, fmt.Sprintf("failed to fetch key %v", key))
+		return
+	}
+
+	// the summary commands of each type, length first
+	var cmds []valkey.Completed
+	switch sample.Type {
+	case "string":
+		cmds = []valkey.Completed{client.B().Get().Key(key).Build()}
+	case "hash":
+		cmds = []valkey.Completed{
+			client.B().Hlen().Key(key).Build(),
+			client.B().Hrandfield().Key(key).Count(randomSummarySize).Build(),
+		}
+	case "list":
+		cmds = []valkey.Completed{
+			client.B().Llen().Key(key).Build(),
+			client.B().Lrange().Key(key).Start(0).Stop(randomSummarySize - 1).Build(),
+		}
+	case "set":
+		cmds = []valkey.Completed{
+			client.B().Scard().Key(key).Build(),
+			client.B().Srandmember().Key(key).Count(randomSummarySize).Build(),
+		}
+	case "zset":
+		cmds = []valkey.Completed{
+			client.B().Zcard().Key(key).Build(),
+			client.B().Zrange().Key(key).Min("0").Max(fmt.Sprint(randomSummarySize - 1)).Build(),
+		}
+	case "none":
+		// expired or deleted since RANDOMKEY, the client can simply ask again
+		fail(http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return
+	default:
+		writeJSON(w, http.StatusOK, sample)
+		return
+	}
+
+	resps := client.DoMulti(ctx, cmds...)
+	if sample.Type == "string" {
+		value, err := resps[0].ToString()
+		if err != nil {
+			log.Printf("Failed to fetch value for key %v, err = %v\n", key, valkeyErr(err))
+			fail(http.StatusBadGateway, fmt.Sprintf("failed to fetch value for key %v", key))
+			return
+		}
+		sample.Value = &value
+		writeJSON(w, http.StatusOK, sample)
+		return
+	}
+
+	length, err := resps[0].AsInt64()
+	if err != nil {
+		log.Printf("Failed to fetch length of key %v, err = %v\n", key, valkeyErr(err))
+		fail(http.StatusBadGateway, fmt.Sprintf("failed to fetch key %v", key))
+		return
+	}
+	sample.Length = &length
+	if sample.Elements, err = resps[1].AsStrSlice(); err != nil {
+		log.Printf("Failed to fetch elements of key %v, err = %v\n", key, valkeyErr(err))
+		fail(http.StatusBadGateway, fmt.Sprintf("failed to fetch key %v", key))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sample)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// buckets idle for longer than this are dropped
+const rateLimitIdle = 5 * time.Minute
+
+// token bucket of a single client
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// per client token buckets, see RATE_LIMIT_RPS and RATE_LIMIT_BURST
+type rateLimiter struct {
+	rps       float64
+	burst     float64
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(rps float64, burst float64) *rateLimiter {
+	return &rateLimiter{rps: rps, burst: burst, buckets: make(map[string]*tokenBucket), lastSweep: time.Now()}
+}
+
+// take a token of the client, otherwise returns the time until the next token
+func (l *rateLimiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > rateLimitIdle {
+		for key, bucket := range l.buckets {
+			if now.Sub(bucket.lastSeen) > rateLimitIdle {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, lastSeen: now}
+		l.buckets[client] = bucket
+	}
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.lastSeen).Seconds()*l.rps)
+	bucket.lastSeen = now
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / l.rps * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+func floatFromEnv(name string, fallback float64) float64 {
+	value := os.Getenv(name)
+	if len(value) < 1 {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed < 0 {
+		log.Printf("Invalid %v=%v, using %v\n", name, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// RATE_LIMIT_RPS=0 disables the rate limiting
+func loadRateLimiter() *rateLimiter {
+	rps := floatFromEnv("RATE_LIMIT_RPS", 50)
+	if rps == 0 {
+		return nil
+	}
+	return newRateLimiter(rps, math.Max(1, floatFromEnv("RATE_LIMIT_BURST", 100)))
+}
+
+// see loadRateLimiter
+var limiter *rateLimiter
+
+// reject clients that exceed their rate with 429, health checks are never limited
+func withRateLimit(next http.Handler) http.Handler {
+	if limiter == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" || r.URL.Path == "/ping" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if ok, wait := limiter.allow(clientIP(r, trustProxy)); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+)
+
+// number of recently viewed keys kept
+const recentKeysSize = 20
+
+func recentKey() string {
+	return internalKey("recent")
+}
+
+// move key to the front of the recently viewed keys
+// failures are only logged, tracking must not break viewing the key
+func recordRecentKey(ctx context.Context, client ValkeyClient, key string) {
+	for _, resp := range client.DoMulti(ctx,
+		client.B().Lrem().Key(recentKey()).Count(0).Element(key).Build(),
+		client.B().Lpush().Key(recentKey()).Element(key).Build(),
+		client.B().Ltrim().Key(recentKey()).Start(0).Stop(recentKeysSize-1).Build(),
+	) {
+		if err := resp.Error(); err != nil {
+			log.Printf("Failed to record recently viewed key %v, err = %v\n", key, valkeyErr(err))
+			return
+		}
+	}
+}
+
+// recently viewed keys, newest first
+func getRecentKeys(w http.ResponseWriter, r *http.Request) {
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	keys, err := client.Do(ctx, client.B().Lrange().Key(recentKey()).Start(0).Stop(-1).Build()).AsStrSlice()
+	if err != nil {
+		log.Printf("Failed to fetch recently viewed keys, err = %v\n", valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to fetch recently viewed keys")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, keys)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// fields of INFO replication, the master_* fields are only set on replicas
+type ReplicationInfo struct {
+	Role             string `json:"role"`
+	ConnectedSlaves  int64  `json:"connected_slaves"`
+	MasterHost       string `json:"master_host,omitempty"`
+	MasterPort       int64  `json:"master_port,omitempty"`
+	MasterLinkStatus string `json:"master_link_status,omitempty"`
+	ReplBacklogSize  int64  `json:"repl_backlog_size"`
+}
+
+// a replica needs its link to the primary, a primary at least one replica
+func (info ReplicationInfo) ok() bool {
+	if info.Role == "slave" {
+		return info.MasterLinkStatus == "up"
+	}
+	return info.ConnectedSlaves >= 1
+}
+
+func fetchReplicationInfo(ctx context.Context, client ValkeyClient) (ReplicationInfo, error) {
+	raw, err := client.Do(ctx, client.B().Info().Section("replication").Build()).ToString()
+	if err != nil {
+		return ReplicationInfo{}, err
+	}
+	fields := parseInfo(raw)
+	info := ReplicationInfo{
+		Role:             fields["role"],
+		MasterHost:       fields["master_host"],
+		MasterLinkStatus: fields["master_link_status"],
+	}
+	// missing fields stay 0
+	info.ConnectedSlaves, _ = strconv.ParseInt(fields["connected_slaves"], 10, 64)
+	info.MasterPort, _ = strconv.ParseInt(fields["master_port"], 10, 64)
+	info.ReplBacklogSize, _ = strconv.ParseInt(fields["repl_backlog_size"], 10, 64)
+	return info, nil
+}
+
+func replicationInfo(w http.ResponseWriter, r *http.Request) {
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	info, err := fetchReplicationInfo(ctx, client)
+	if err != nil {
+		log.Printf("Failed to fetch replication info, err = %v\n", valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, "failed to fetch replication info")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// number of workers fetching the values of scanned key pages
+const scanWorkers = 8
+
+// COUNT hint of the SCAN calls, see VALKEY_SCAN_COUNT
+var scanCount int64 = 100
+
+// time after which /api/v1/key-values/count stops scanning, see MAX_COUNT_SCAN_MS
+var countScanTimeout = 5 * time.Second
+
+// fetch the values of scanned key pages in parallel
+// every worker pipelines the TYPEs and GETs of one page at a time, the results are unordered
+// keys of other types than string are passed on with their type and without value
+func workerPool(ctx context.Context, client ValkeyClient, nWorkers int, pages <-chan []string) <-chan KeyValue {
+	results := make(chan KeyValue)
+
+	var wg sync.WaitGroup
+	for i := 0; i < nWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for keys := range pages {
+				cmds := make(valkey.Commands, 0, 2*len(keys))
+				for _, key := range keys {
+					cmds = append(cmds, client.B().Type().Key(key).Build(), client.B().Get().Key(key).Build())
+				}
+				resps := client.DoMulti(ctx, cmds...)
+				for i, key := range keys {
+					keyType, err := resps[2*i].ToString()
+					if err != nil {
+						log.Printf("Failed to fetch type of key %v, err = %v\n", key, valkeyErr(err))
+						continue
+					}
+					switch keyType {
+					case "none":
+						// expired since the scan
+						continue
+					case "string":
+					default:
+						results <- KeyValue{Key: key, Type: keyType}
+						continue
+					}
+					value, err := resps[2*i+1].ToString()
+					if err != nil {
+						log.Printf("Failed to fetch value for key %v, err = %v\n", key, valkeyErr(err))
+						continue
+					}
+					results <- KeyValue{Key: key, Value: value}
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// feed the pages of a full SCAN into pages, closes pages when done
+func scanPages(ctx context.Context, client ValkeyClient, pages chan<- []string) error {
+	defer close(pages)
+
+	var cursor uint64
+	for {
+		// a higher COUNT means fewer round trips but more work per call, which blocks the server longer
+		entry, err := client.Do(ctx, client.B().Scan().Cursor(cursor).Count(scanCount).Build()).AsScanEntry()
+		if err != nil {
+			return err
+		}
+		if len(entry.Elements) > 0 {
+			pages <- entry.Elements
+		}
+		cursor = entry.Cursor
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// read VALKEY_SCAN_COUNT
+func initScanCount() {
+	countStr := os.Getenv("VALKEY_SCAN_COUNT")
+	if len(countStr) < 1 {
+		return
+	}
+	count, err := strconv.ParseInt(countStr, 10, 64)
+	if err != nil || count < 1 {
+		log.Printf("Ignoring VALKEY_SCAN_COUNT=%v, expected a positive integer\n", countStr)
+		return
+	}
+	scanCount = count
+}
+
+// read MAX_COUNT_SCAN_MS
+func initCountScanTimeout() {
+	msStr := os.Getenv("MAX_COUNT_SCAN_MS")
+	if len(msStr) < 1 {
+		return
+	}
+	ms, err := strconv.ParseInt(msStr, 10, 64)
+	if err != nil || ms < 1 {
+		log.Printf("Ignoring MAX_COUNT_SCAN_MS=%v, expected a positive integer\n", msStr)
+		return
+	}
+	countScanTimeout = time.Duration(ms) * time.Millisecond
+}
+
+// deadline of a SCAN limited by countScanTimeout
+// the last SCAN starts right before the time limit and gets one command timeout on top
+func countScanDeadline() time.Duration {
+	return countScanTimeout + valkeyCmdTimeout
+}
+
+// number of keys matching ?pattern=, unlike DBSIZE limited to a namespace
+// the count is incomplete if the SCAN takes longer than countScanTimeout
+func countKeys(w http.ResponseWriter, r *http.Request) {
+	pattern := r.URL.Query().Get("pattern")
+	if len(pattern) < 1 {
+		pattern = "*"
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), countScanDeadline())
+	defer cancel()
+
+	stopAt := time.Now().Add(countScanTimeout)
+	var count int64
+	var cursor uint64
+	complete := true
+	for {
+		entry, err := client.Do(ctx, client.B().Scan().Cursor(cursor).Match(pattern).Count(scanCount).Build()).AsScanEntry()
+		if err != nil {
+			log.Printf("Failed to scan keys matching %v, err = %v\n", pattern, valkeyErr(err))
+			writeJSONError(w, http.StatusBadGateway, "failed to count keys")
+			return
+		}
+		count += int64(len(entry.Elements))
+		cursor = entry.Cursor
+		if cursor == 0 {
+			break
+		}
+		if time.Now().After(stopAt) {
+			complete = false
+			break
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"count":    count,
+		"pattern":  pattern,
+		"complete": complete,
+	})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+	"github.com/valkey-io/valkey-go/mock"
+)
+
+// keys fetched per BenchmarkWorkerPool iteration
+const benchmarkKeys = 10000
+
+func BenchmarkWorkerPool(b *testing.B) {
+	client := NewMockValkeyClient()
+	keys := make([]string, 0, benchmarkKeys)
+	for i := 0; i < benchmarkKeys; i++ {
+		key := fmt.Sprintf("key_%d", i)
+		client.store[key] = fmt.Sprintf("value_%d", i)
+		keys = append(keys, key)
+	}
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// pages of scanCount keys like scanPages
+		pages := make(chan []string)
+		go func() {
+			defer close(pages)
+			for start := 0; start < len(keys); start += int(scanCount) {
+				pages <- keys[start:min(start+int(scanCount), len(keys))]
+			}
+		}()
+
+		fetched := 0
+		for range workerPool(ctx, client, scanWorkers, pages) {
+			fetched++
+		}
+		if fetched != benchmarkKeys {
+			b.Fatalf("fetched %v keys, want %v", fetched, benchmarkKeys)
+		}
+	}
+}
+
+// answers every SCAN after a delay with a cursor that never ends the iteration
+type endlessScanClient struct {
+	ValkeyClient
+	delay time.Duration
+}
+
+func (c *endlessScanClient) Do(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+	select {
+	case <-time.After(c.delay):
+		return mock.Result(mock.ValkeyArray(
+			mock.ValkeyBlobString("1"),
+			mock.ValkeyArray(mock.ValkeyBlobString("key")),
+		))
+	case <-ctx.Done():
+		return mock.ErrorResult(ctx.Err())
+	}
+}
+
+func TestCountKeysIncomplete(t *testing.T) {
+	previousCmd, previousScan := valkeyCmdTimeout, countScanTimeout
+	// a time limit longer than the command timeout
+	valkeyCmdTimeout, countScanTimeout = 50*time.Millisecond, 150*time.Millisecond
+	t.Cleanup(func() { valkeyCmdTimeout, countScanTimeout = previousCmd, previousScan })
+
+	client := &endlessScanClient{ValkeyClient: NewMockValkeyClient(), delay: 10 * time.Millisecond}
+	previous := newClient
+	newClient = func(r *http.Request) (ValkeyClient, error) { return client, nil }
+	t.Cleanup(func() { newClient = previous })
+
+	w := httptest.NewRecorder()
+	countKeys(w, httptest.NewRequest(http.MethodGet, "/api/v1/key-values/count", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %v, want %v: %v", w.Code, http.StatusOK, w.Body.String())
+	}
+	var body struct {
+		Count    int64 `json:"count"`
+		Complete bool  `json:"complete"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding %v: %v", w.Body.String(), err)
+	}
+	if body.Complete || body.Count < 1 {
+		t.Errorf("count = %v, complete = %v, want an incomplete count", body.Count, body.Complete)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// Sentinel setup from VALKEY_SENTINEL_MASTER, VALKEY_SENTINEL_USERNAME and VALKEY_SENTINEL_PASSWORD,
+// with VALKEY_ENV_PREFIX e.g. MY_APP_VALKEY_SENTINEL_MASTER
+// the sentinel nodes may require other credentials than the data nodes
+type SentinelDetails struct {
+	MasterSet string `json:"-"`
+	Username  string `json:"-"`
+	Password  string `json:"-"`
+}
+
+// prefix is the one of the credentials, see envPrefix
+func sentinelFromEnv(prefix string) SentinelDetails {
+	return SentinelDetails{
+		MasterSet: os.Getenv(prefix + "_SENTINEL_MASTER"),
+		Username:  os.Getenv(prefix + "_SENTINEL_USERNAME"),
+		Password:  os.Getenv(prefix + "_SENTINEL_PASSWORD"),
+	}
+}
+
+// with a master set the address of the credentials is the one of a sentinel
+func (s SentinelDetails) option() (valkey.SentinelOption, bool) {
+	if len(s.MasterSet) < 1 {
+		return valkey.SentinelOption{}, false
+	}
+	return valkey.SentinelOption{MasterSet: s.MasterSet, Username: s.Username, Password: s.Password}, true
+}
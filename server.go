@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// start the http server, with TLS when configured
+// TLS_CERT_FILE and TLS_KEY_FILE serve a static certificate, TLS_AUTO_CERT_DOMAIN
+// provisions one from Let's Encrypt. With TLS_PORT set as well, TLS is served on
+// TLS_PORT and the plain port only redirects to https.
+func serve(port string) error {
+	certFile := os.Getenv("TLS_CERT_FILE")
+	keyFile := os.Getenv("TLS_KEY_FILE")
+	autoCertDomain := os.Getenv("TLS_AUTO_CERT_DOMAIN")
+	tlsPort := os.Getenv("TLS_PORT")
+	// bind to a single interface, all interfaces by default
+	host := os.Getenv("HTTP_ADDR")
+
+	server := newServer(net.JoinHostPort(host, port), rootHandler())
+	done := shutdownOnSignal(server)
+	if len(autoCertDomain) < 1 && (len(certFile) < 1 || len(keyFile) < 1) {
+		log.Printf("Listening on %v\n", server.Addr)
+		return waitForShutdown(server.ListenAndServe(), done)
+	}
+
+	redirect := redirectToHTTPS(tlsPort)
+	if len(autoCertDomain) > 0 {
+		cacheDir := os.Getenv("TLS_AUTO_CERT_CACHE_DIR")
+		if len(cacheDir) < 1 {
+			cacheDir = "certs"
+		}
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(autoCertDomain),
+			Cache:      autocert.DirCache(cacheDir),
+		}
+		server.TLSConfig = manager.TLSConfig()
+		// the certificates are provided by the manager
+		certFile, keyFile = "", ""
+		// answer the ACME http-01 challenges on the plain port
+		redirect = manager.HTTPHandler(redirect)
+		log.Printf("Using Let's Encrypt certificates for %v\n", autoCertDomain)
+	}
+
+	if len(tlsPort) > 0 {
+		server.Addr = net.JoinHostPort(host, tlsPort)
+		redirectServer := newServer(net.JoinHostPort(host, port), redirect)
+		shutdownOnSignal(redirectServer)
+		go func() {
+			log.Printf("Redirecting %v to https\n", redirectServer.Addr)
+			if err := redirectServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	log.Printf("Listening on %v (TLS)\n", server.Addr)
+	return waitForShutdown(server.ListenAndServeTLS(certFile, keyFile), done)
+}
+
+// the registered routes with the middlewares of every request
+func rootHandler() http.Handler {
+	return withRequestID(withRateLimit(withCORS(http.DefaultServeMux)))
+}
+
+// Cloud Foundry kills the app 10 seconds after SIGTERM
+const shutdownTimeout = 8 * time.Second
+
+// stop accepting requests on SIGTERM or SIGINT and let the running ones finish
+// the returned channel is closed once the server is shut down
+func shutdownOnSignal(server *http.Server) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+		defer stop()
+		<-ctx.Done()
+
+		log.Printf("Shutting down %v\n", server.Addr)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Failed to shut down %v gracefully: %v\n", server.Addr, err)
+		}
+		close(done)
+	}()
+	return done
+}
+
+// ListenAndServe returns as soon as the shutdown starts, wait for the running requests
+func waitForShutdown(err error, done <-chan struct{}) error {
+	if errors.Is(err, http.ErrServerClosed) {
+		<-done
+		removePIDFile()
+		return nil
+	}
+	return err
+}
+
+// http server with timeouts, guards against slow or idle clients holding connections
+func newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       durationFromEnv("HTTP_READ_TIMEOUT", 15*time.Second),
+		WriteTimeout:      durationFromEnv("HTTP_WRITE_TIMEOUT", 60*time.Second),
+		IdleTimeout:       durationFromEnv("HTTP_IDLE_TIMEOUT", 120*time.Second),
+		ReadHeaderTimeout: durationFromEnv("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+	}
+}
+
+// redirect plain http requests to the TLS port
+func redirectToHTTPS(tlsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if len(tlsPort) > 0 && tlsPort != "443" {
+			host = net.JoinHostPort(host, tlsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
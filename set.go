@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+type SetViewModel struct {
+	Key     string
+	Members []string
+}
+
+// members of a set with a form for set operations
+func renderSet(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		renderError(w, r, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	members, err := client.Do(ctx, client.B().Smembers().Key(key).Build()).AsStrSlice()
+	if err != nil {
+		log.Printf("Failed to fetch members of set %v, err = %v\n", key, valkeyErr(err))
+		renderError(w, r, http.StatusBadGateway, fmt.Sprintf("failed to fetch set %v", key))
+		return
+	}
+	sort.Strings(members)
+
+	renderTemplate(w, "set", "base", SetViewModel{Key: key, Members: members})
+}
+
+// SUNION, SINTER or SDIFF of the given keys, the STORE variant if "store" is set
+func setOperation(op string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Keys  []string `json:"keys"`
+			Store string   `json:"store"`
+		}
+		if !decodeJSON(w, r, &body) {
+			return
+		}
+		if len(body.Keys) < 1 {
+			writeJSONError(w, http.StatusBadRequest, "keys must not be empty")
+			return
+		}
+
+		client, err := newClient(r)
+		if err != nil {
+			log.Printf("Failed to create connection: %v", err)
+			writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+			return
+		}
+		defer client.Close()
+
+		ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+		defer cancel()
+
+		store := len(body.Store) > 0
+		var cmd valkey.Completed
+		switch {
+		case op == "union" && store:
+			cmd = client.B().Sunionstore().Destination(body.Store).Key(body.Keys...).Build()
+		case op == "union":
+			cmd = client.B().Sunion().Key(body.Keys...).Build()
+		case op == "intersect" && store:
+			cmd = client.B().Sinterstore().Destination(body.Store).Key(body.Keys...).Build()
+		case op == "intersect":
+			cmd = client.B().Sinter().Key(body.Keys...).Build()
+		case op == "diff" && store:
+			cmd = client.B().Sdiffstore().Destination(body.Store).Key(body.Keys...).Build()
+		default:
+			cmd = client.B().Sdiff().Key(body.Keys...).Build()
+		}
+
+		resp := client.Do(ctx, cmd)
+		if store {
+			count, err := resp.AsInt64()
+			if err != nil {
+				log.Printf("Failed to store %v of %v into %v, err = %v\n", op, body.Keys, body.Store, valkeyErr(err))
+				writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to store into %v", body.Store))
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"destination": body.Store,
+				"cardinality": count,
+			})
+			return
+		}
+
+		members, err := resp.AsStrSlice()
+		if err != nil {
+			log.Printf("Failed to compute %v of %v, err = %v\n", op, body.Keys, valkeyErr(err))
+			writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to compute %v", op))
+			return
+		}
+		sort.Strings(members)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"members": members})
+	}
+}
+
+// answers 404 for missing keys and 400 for other types, SRANDMEMBER and SPOP would answer an empty reply or WRONGTYPE
+func checkSetType(ctx context.Context, w http.ResponseWriter, client ValkeyClient, key string) bool {
+	keyType, err := client.Do(ctx, client.B().Type().Key(key).Build()).ToString()
+	if err != nil {
+		log.Printf("Failed to fetch type of key %v, err = %v\n", key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch type of key %v", key))
+		return false
+	}
+	if keyType == "none" {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return false
+	}
+	if keyType != "set" {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("key %v is a %v, not a set", key, keyType))
+		return false
+	}
+	return true
+}
+
+// ?count= of the random member endpoints, 1 by default
+func parseMemberCount(r *http.Request) (int64, error) {
+	countStr := r.URL.Query().Get("count")
+	if len(countStr) < 1 {
+		return 1, nil
+	}
+	return strconv.ParseInt(countStr, 10, 64)
+}
+
+// SRANDMEMBER, a negative ?count= may return a member several times
+func getRandomSetMembers(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	count, err := parseMemberCount(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "query parameter count must be an integer")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	if !checkSetType(ctx, w, client, key) {
+		return
+	}
+	members, err := client.Do(ctx, client.B().Srandmember().Key(key).Count(count).Build()).AsStrSlice()
+	if err != nil {
+		log.Printf("Failed to fetch random members of set %v, err = %v\n", key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch random members of set %v", key))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, members)
+}
+
+// SPOP removes and returns up to ?count= random members
+func popSetMembers(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	count, err := parseMemberCount(r)
+	if err != nil || count < 1 {
+		writeJSONError(w, http.StatusBadRequest, "query parameter count must be a positive integer")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	if !checkSetType(ctx, w, client, key) {
+		return
+	}
+	members, err := replyStrSlice(client.Do(ctx, client.B().Spop().Key(key).Count(count).Build()))
+	if err != nil {
+		log.Printf("Failed to pop members of set %v, err = %v\n", key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to pop members of set %v", key))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, members)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// counters of a single handler
+type handlerStats struct {
+	requests      atomic.Int64
+	errors        atomic.Int64
+	totalDuration atomic.Int64
+}
+
+// per handler request statistics
+// the handlers are registered on startup, afterwards the map is only read
+type Stats struct {
+	handlers map[string]*handlerStats
+}
+
+var stats = Stats{handlers: make(map[string]*handlerStats)}
+
+// keyspace figures, refreshed in the background by statsRefresher
+var (
+	currentKeyCount  atomic.Int64
+	usedMemoryBytes  atomic.Int64
+	statsRefreshedAt atomic.Int64
+	valkeyReachable  atomic.Bool
+	// LASTSAVE, 0 until fetched
+	lastSaveAt atomic.Int64
+	// see ReplicationInfo.ok, nil until fetched
+	replicationOK atomic.Pointer[bool]
+	// connected_clients and blocked_clients of INFO clients, see /metrics
+	connectedClients atomic.Int64
+	blockedClients   atomic.Int64
+)
+
+type lastErrorInfo struct {
+	time time.Time
+	msg  string
+}
+
+// last Valkey error and the number of errors since the last successful request
+var (
+	lastError         atomic.Pointer[lastErrorInfo]
+	consecutiveErrors atomic.Int64
+	// all errors ever recorded, tells whether an error was recorded while a request ran
+	errorSequence atomic.Int64
+)
+
+func setLastError(err error) {
+	if err == nil {
+		return
+	}
+	lastError.Store(&lastErrorInfo{time: time.Now(), msg: err.Error()})
+	consecutiveErrors.Add(1)
+	errorSequence.Add(1)
+}
+
+// reset consecutiveErrors unless an error was recorded since errorSequence was seq
+// errors of concurrent requests keep the count as well, a success has to run without any
+func resetConsecutiveErrors(seq int64) {
+	if errorSequence.Load() == seq {
+		consecutiveErrors.Store(0)
+	}
+}
+
+type HandlerStatsViewModel struct {
+	Requests     int64   `json:"requests"`
+	Errors       int64   `json:"errors"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+// keeps the status code for the statistics
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// allow streaming handlers to flush through the recorder
+func (rec *statusRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// count requests, server errors and latency of a handler
+func instrument(name string, handler http.HandlerFunc) http.HandlerFunc {
+	counters := &handlerStats{}
+	stats.handlers[name] = counters
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		seq := errorSequence.Load()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler(rec, r)
+
+		counters.requests.Add(1)
+		counters.totalDuration.Add(int64(time.Since(start)))
+		if rec.status >= http.StatusInternalServerError {
+			counters.errors.Add(1)
+		} else {
+			// a 404 of a missing key or a skipped page may still have logged a Valkey error
+			resetConsecutiveErrors(seq)
+		}
+	}
+}
+
+func renderStats(w http.ResponseWriter, r *http.Request) {
+	handlers := make(map[string]HandlerStatsViewModel, len(stats.handlers))
+	for name, counters := range stats.handlers {
+		requests := counters.requests.Load()
+		viewModel := HandlerStatsViewModel{
+			Requests: requests,
+			Errors:   counters.errors.Load(),
+		}
+		if requests > 0 {
+			viewModel.AvgLatencyMs = float64(counters.totalDuration.Load()) / float64(requests) / float64(time.Millisecond)
+		}
+		handlers[name] = viewModel
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"handlers":          handlers,
+		"key_count":         currentKeyCount.Load(),
+		"used_memory_bytes": usedMemoryBytes.Load(),
+	})
+}
+
+// report the state of the last background refresh, Valkey is not contacted here
+func renderHealth(w http.ResponseWriter, r *http.Request) {
+	status := "ok"
+	code := http.StatusOK
+	if !valkeyReachable.Load() {
+		status = "unavailable"
+		code = http.StatusServiceUnavailable
+	}
+
+	var refreshedAt interface{}
+	if unix := statsRefreshedAt.Load(); unix > 0 {
+		refreshedAt = time.Unix(unix, 0).UTC().Format(time.RFC3339)
+	}
+
+	var lastSave interface{}
+	if unix := lastSaveAt.Load(); unix > 0 {
+		lastSave = time.Unix(unix, 0).UTC().Format(time.RFC3339)
+	}
+
+	var lastErr interface{}
+	if info := lastError.Load(); info != nil {
+		lastErr = map[string]interface{}{
+			"time":    info.time.UTC().Format(time.RFC3339),
+			"message": info.msg,
+		}
+	}
+
+	writeJSON(w, code, map[string]interface{}{
+		"status":             status,
+		"key_count":          currentKeyCount.Load(),
+		"used_memory_bytes":  usedMemoryBytes.Load(),
+		"refreshed_at":       refreshedAt,
+		"last_save":          lastSave,
+		"replication_ok":     replicationOK.Load(),
+		"dry_run":            dryRun,
+		"last_error":         lastErr,
+		"consecutive_errors": consecutiveErrors.Load(),
+		"uptime_seconds":     uptimeSeconds(),
+		"started_at":         startTime.UTC().Format(time.RFC3339),
+	})
+}
+
+// periodically fetch the key count and memory usage
+// so /stats, /health and the index page do not have to ask Valkey on every request
+func statsRefresher(interval time.Duration) {
+	for {
+		refreshStats()
+		time.Sleep(interval)
+	}
+}
+
+func refreshStats() {
+	seq := errorSequence.Load()
+	client, err := newClient(nil)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		valkeyReachable.Store(false)
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(context.Background(), valkeyCmdTimeout)
+	defer cancel()
+	keyCount, err := client.Do(ctx, client.B().Dbsize().Build()).AsInt64()
+	if err != nil {
+		log.Printf("Failed to fetch key count, err = %v\n", valkeyErr(err))
+		valkeyReachable.Store(false)
+		return
+	}
+	currentKeyCount.Store(keyCount)
+
+	info, err := client.Do(ctx, client.B().Info().Section("memory").Build()).ToString()
+	if err != nil {
+		log.Printf("Failed to fetch memory info, err = %v\n", valkeyErr(err))
+	} else if usedMemory, err := strconv.ParseInt(parseInfo(info)["used_memory"], 10, 64); err == nil {
+		usedMemoryBytes.Store(usedMemory)
+	}
+
+	info, err = client.Do(ctx, client.B().Info().Section("clients").Build()).ToString()
+	if err != nil {
+		log.Printf("Failed to fetch clients info, err = %v\n", valkeyErr(err))
+	} else {
+		fields := parseInfo(info)
+		if connected, err := strconv.ParseInt(fields["connected_clients"], 10, 64); err == nil {
+			connectedClients.Store(connected)
+		}
+		if blocked, err := strconv.ParseInt(fields["blocked_clients"], 10, 64); err == nil {
+			blockedClients.Store(blocked)
+		}
+	}
+
+	if unix, err := client.Do(ctx, client.B().Lastsave().Build()).AsInt64(); err == nil {
+		lastSaveAt.Store(unix)
+	} else {
+		log.Printf("Failed to fetch last save, err = %v\n", valkeyErr(err))
+	}
+
+	if replication, err := fetchReplicationInfo(ctx, client); err == nil {
+		ok := replication.ok()
+		replicationOK.Store(&ok)
+	} else {
+		log.Printf("Failed to fetch replication info, err = %v\n", valkeyErr(err))
+	}
+
+	valkeyReachable.Store(true)
+	statsRefreshedAt.Store(time.Now().Unix())
+	resetConsecutiveErrors(seq)
+}
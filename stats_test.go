@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInstrumentConsecutiveErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		valkey  error
+		wantErr int64
+	}{
+		{name: "success resets", status: http.StatusOK, wantErr: 0},
+		{name: "success with a valkey error", status: http.StatusOK, valkey: errors.New("READONLY"), wantErr: 2},
+		{name: "not found with a valkey error", status: http.StatusNotFound, valkey: errors.New("READONLY"), wantErr: 2},
+		{name: "server error", status: http.StatusBadGateway, wantErr: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consecutiveErrors.Store(1)
+			handler := instrument("test", func(w http.ResponseWriter, r *http.Request) {
+				if tt.valkey != nil {
+					valkeyErr(tt.valkey)
+				}
+				w.WriteHeader(tt.status)
+			})
+
+			handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if got := consecutiveErrors.Load(); got != tt.wantErr {
+				t.Errorf("consecutiveErrors = %v, want %v", got, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,743 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// number of entries on the stream page, newest first
+const streamPageEntries = 20
+
+const typeStream = "stream"
+
+// maximum number of entries and longest wait of a stream read
+const (
+	maxStreamReadCount = 1000
+	maxStreamReadBlock = 30 * time.Second
+)
+
+type StreamGroup struct {
+	Name            string `json:"name"`
+	Consumers       int64  `json:"consumers"`
+	Pending         int64  `json:"pending"`
+	LastDeliveredID string `json:"last_delivered_id"`
+}
+
+type StreamConsumer struct {
+	Name string `json:"name"`
+	// size of the consumer's pending entries list
+	Pending int64 `json:"pending"`
+	IdleMs  int64 `json:"idle_ms"`
+}
+
+// entry of XPENDING, delivered but not acknowledged yet
+type PendingEntry struct {
+	ID         string `json:"id"`
+	Consumer   string `json:"consumer"`
+	IdleMs     int64  `json:"idle_ms"`
+	Deliveries int64  `json:"deliveries"`
+}
+
+type StreamGroupView struct {
+	StreamGroup
+	Members []StreamConsumer
+}
+
+// summary of XINFO STREAM
+type StreamSummary struct {
+	FirstEntryID    string
+	LastEntryID     string
+	LastGeneratedID string
+	Groups          int64
+	Consumers       int64
+}
+
+type StreamViewModel struct {
+	Key    string
+	Length int64
+	// nil if XINFO STREAM failed
+	Summary *StreamSummary
+	Entries []valkey.XRangeEntry
+	Groups  []StreamGroupView
+}
+
+// XINFO and XGROUP fail with an error instead of an empty reply for missing keys
+func isMissingStream(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "no such key") || strings.Contains(err.Error(), "requires the key to exist"))
+}
+
+// XINFO GROUPS, the reply is a list of maps
+func fetchStreamGroups(ctx context.Context, client ValkeyClient, key string) ([]StreamGroup, error) {
+	infos, err := client.Do(ctx, client.B().XinfoGroups().Key(key).Build()).ToArray()
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]StreamGroup, 0, len(infos))
+	for _, info := range infos {
+		fields, err := info.AsMap()
+		if err != nil {
+			return nil, err
+		}
+		group := StreamGroup{}
+		if name, ok := fields["name"]; ok {
+			group.Name, _ = name.ToString()
+		}
+		if consumers, ok := fields["consumers"]; ok {
+			group.Consumers, _ = consumers.AsInt64()
+		}
+		if pending, ok := fields["pending"]; ok {
+			group.Pending, _ = pending.AsInt64()
+		}
+		if id, ok := fields["last-delivered-id"]; ok {
+			group.LastDeliveredID, _ = id.ToString()
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// XINFO CONSUMERS of a group
+func fetchStreamConsumers(ctx context.Context, client ValkeyClient, key string, group string) ([]StreamConsumer, error) {
+	infos, err := client.Do(ctx, client.B().XinfoConsumers().Key(key).Group(group).Build()).ToArray()
+	if err != nil {
+		return nil, err
+	}
+	consumers := make([]StreamConsumer, 0, len(infos))
+	for _, info := range infos {
+		fields, err := info.AsMap()
+		if err != nil {
+			return nil, err
+		}
+		consumer := StreamConsumer{}
+		if name, ok := fields["name"]; ok {
+			consumer.Name, _ = name.ToString()
+		}
+		if pending, ok := fields["pending"]; ok {
+			consumer.Pending, _ = pending.AsInt64()
+		}
+		if idle, ok := fields["idle"]; ok {
+			consumer.IdleMs, _ = idle.AsInt64()
+		}
+		consumers = append(consumers, consumer)
+	}
+	return consumers, nil
+}
+
+// first and last entry of XINFO STREAM, the consumers are counted from the groups
+func fetchStreamSummary(ctx context.Context, client ValkeyClient, key string, groups []StreamGroup) (StreamSummary, error) {
+	summary := StreamSummary{}
+	fields, err := client.Do(ctx, client.B().XinfoStream().Key(key).Build()).AsMap()
+	if err != nil {
+		return summary, err
+	}
+	if id, ok := fields["last-generated-id"]; ok {
+		summary.LastGeneratedID, _ = id.ToString()
+	}
+	if count, ok := fields["groups"]; ok {
+		summary.Groups, _ = count.AsInt64()
+	}
+	// nil for empty streams
+	if entry, ok := fields["first-entry"]; ok {
+		if first, err := entry.AsXRangeEntry(); err == nil {
+			summary.FirstEntryID = first.ID
+		}
+	}
+	if entry, ok := fields["last-entry"]; ok {
+		if last, err := entry.AsXRangeEntry(); err == nil {
+			summary.LastEntryID = last.ID
+		}
+	}
+	for _, group := range groups {
+		summary.Consumers += group.Consumers
+	}
+	return summary, nil
+}
+
+// latest entries and consumer groups of a stream
+func renderStream(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		renderError(w, r, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	resps := client.DoMulti(ctx,
+		client.B().Xlen().Key(key).Build(),
+		client.B().Xrevrange().Key(key).End("+").Start("-").Count(streamPageEntries).Build(),
+	)
+	viewModel := StreamViewModel{Key: key}
+	if viewModel.Length, err = resps[0].AsInt64(); err != nil {
+		log.Printf("Failed to fetch length of stream %v, err = %v\n", key, valkeyErr(err))
+		renderError(w, r, http.StatusBadGateway, fmt.Sprintf("failed to fetch stream %v", key))
+		return
+	}
+	if viewModel.Entries, err = resps[1].AsXRange(); err != nil {
+		log.Printf("Failed to fetch entries of stream %v, err = %v\n", key, valkeyErr(err))
+		renderError(w, r, http.StatusBadGateway, fmt.Sprintf("failed to fetch stream %v", key))
+		return
+	}
+	// the entries are still useful without the groups
+	groups, err := fetchStreamGroups(ctx, client, key)
+	if err != nil {
+		log.Printf("Failed to fetch groups of stream %v, err = %v\n", key, valkeyErr(err))
+	}
+	for _, group := range groups {
+		view := StreamGroupView{StreamGroup: group}
+		if view.Members, err = fetchStreamConsumers(ctx, client, key, group.Name); err != nil {
+			log.Printf("Failed to fetch consumers of group %v, err = %v\n", group.Name, valkeyErr(err))
+		}
+		viewModel.Groups = append(viewModel.Groups, view)
+	}
+	if summary, err := fetchStreamSummary(ctx, client, key, groups); err == nil {
+		viewModel.Summary = &summary
+	} else {
+		log.Printf("Failed to fetch info of stream %v, err = %v\n", key, valkeyErr(err))
+	}
+
+	renderTemplate(w, "stream", "base", viewModel)
+}
+
+// XINFO STREAM FULL with up to 10 entries and pending entries per group
+func getStreamInfo(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	info, err := client.Do(ctx, client.B().XinfoStream().Key(key).Full().Count(10).Build()).ToAny()
+	if isMissingStream(err) {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch info of stream %v, err = %v\n", key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch info of stream %v", key))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+func getStreamGroups(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	groups, err := fetchStreamGroups(ctx, client, key)
+	if isMissingStream(err) {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch groups of stream %v, err = %v\n", key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch groups of stream %v", key))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, groups)
+}
+
+// XGROUP CREATE, "start" defaults to $ to only deliver new entries
+func createStreamGroup(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	var body struct {
+		Group string `json:"group"`
+		Start string `json:"start"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if len(body.Group) < 1 {
+		writeJSONError(w, http.StatusBadRequest, "group must not be empty")
+		return
+	}
+	if len(body.Start) < 1 {
+		body.Start = "$"
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	err = client.Do(ctx, client.B().XgroupCreate().Key(key).Group(body.Group).Id(body.Start).Build()).Error()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		writeJSONError(w, http.StatusConflict, fmt.Sprintf("group %v already exists", body.Group))
+		return
+	}
+	if isMissingStream(err) {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create group %v of stream %v, err = %v\n", body.Group, key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to create group %v", body.Group))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"group": body.Group, "start": body.Start})
+}
+
+// XGROUP DESTROY, drops the pending entries of the group as well
+func deleteStreamGroup(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	group := r.PathValue("group")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	destroyed, err := replyInt64(client.Do(ctx, client.B().XgroupDestroy().Key(key).Group(group).Build()))
+	if isMissingStream(err) {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("key %v not found", key))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete group %v of stream %v, err = %v\n", group, key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to delete group %v", group))
+		return
+	}
+	if destroyed == 0 {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("group %v not found", group))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deleted": true})
+}
+
+// XACK of entries delivered to a group
+func ackStreamEntries(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	group := r.PathValue("group")
+
+	var body struct {
+		// XACK only needs the group, the consumer is logged to trace who acknowledged
+		Consumer string   `json:"consumer"`
+		IDs      []string `json:"ids"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if len(body.IDs) < 1 {
+		writeJSONError(w, http.StatusBadRequest, "ids must not be empty")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	acked, err := replyInt64(client.Do(ctx, client.B().Xack().Key(key).Group(group).Id(body.IDs...).Build()))
+	if err != nil {
+		log.Printf("Failed to acknowledge %v in group %v of stream %v, err = %v\n", body.IDs, group, key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to acknowledge entries in group %v", group))
+		return
+	}
+	log.Printf("Acknowledged %v of %v entries in group %v of stream %v for consumer %v\n", acked, len(body.IDs), group, key, body.Consumer)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"acknowledged": acked})
+}
+
+// first 10 pending entries of a group, ?consumer= limits them to one consumer
+func getPendingEntries(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	group := r.PathValue("group")
+	consumer := r.URL.Query().Get("consumer")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	cmd := client.B().Xpending().Key(key).Group(group).Start("-").End("+").Count(10).Build()
+	if len(consumer) > 0 {
+		cmd = client.B().Xpending().Key(key).Group(group).Start("-").End("+").Count(10).Consumer(consumer).Build()
+	}
+	replies, err := client.Do(ctx, cmd).ToArray()
+	if err != nil && strings.HasPrefix(err.Error(), "NOGROUP") {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("group %v not found", group))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch pending entries of group %v, err = %v\n", group, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch pending entries of group %v", group))
+		return
+	}
+
+	// each entry is [id, consumer, idle ms, number of deliveries]
+	entries := make([]PendingEntry, 0, len(replies))
+	for _, reply := range replies {
+		fields, err := reply.ToArray()
+		if err != nil || len(fields) < 4 {
+			log.Printf("Unexpected pending entry in group %v, err = %v\n", group, err)
+			continue
+		}
+		entry := PendingEntry{}
+		entry.ID, _ = fields[0].ToString()
+		entry.Consumer, _ = fields[1].ToString()
+		entry.IdleMs, _ = fields[2].AsInt64()
+		entry.Deliveries, _ = fields[3].AsInt64()
+		entries = append(entries, entry)
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// XGROUP DELCONSUMER, its pending entries are lost
+func deleteStreamConsumer(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	group := r.PathValue("group")
+	consumer := r.PathValue("consumer")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	pending, err := replyInt64(client.Do(ctx, client.B().XgroupDelconsumer().Key(key).Group(group).Consumername(consumer).Build()))
+	if err != nil && strings.HasPrefix(err.Error(), "NOGROUP") {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("group %v not found", group))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete consumer %v of group %v, err = %v\n", consumer, group, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to delete consumer %v", consumer))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deleted": true, "pending": pending})
+}
+
+// field value pairs of the stream form, each row submits a stream_field and a stream_value
+func streamFormFields(r *http.Request) ([]string, error) {
+	names, values := r.PostForm["stream_field"], r.PostForm["stream_value"]
+	if len(names) < 1 {
+		return nil, fmt.Errorf("at least one field is required")
+	}
+	if len(names) != len(values) {
+		return nil, fmt.Errorf("every field needs a value")
+	}
+	pairs := make([]string, 0, 2*len(names))
+	for i, name := range names {
+		if len(strings.TrimSpace(name)) < 1 {
+			return nil, fmt.Errorf("field %v has no name", i+1)
+		}
+		pairs = append(pairs, name, values[i])
+	}
+	return pairs, nil
+}
+
+// XADD with a generated ID, the form shows the ID after the redirect
+func createStreamEntry(w http.ResponseWriter, r *http.Request) {
+	key := r.PostFormValue("key")
+	if len(key) < 1 {
+		renderError(w, r, http.StatusBadRequest, "key is required")
+		return
+	}
+	pairs, err := streamFormFields(r)
+	if err != nil {
+		renderError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		renderError(w, r, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	cmd := client.B().Xadd().Key(key).Id("*").FieldValue()
+	for i := 0; i < len(pairs); i += 2 {
+		cmd = cmd.FieldValue(pairs[i], pairs[i+1])
+	}
+	id, err := replyString(client.Do(ctx, cmd.Build()))
+	if err != nil {
+		log.Printf("Failed to add entry to stream %v, err = %v\n", key, valkeyErr(err))
+		renderError(w, r, http.StatusBadGateway, fmt.Sprintf("failed to add entry to stream %v: %v", key, valkeyErr(err)))
+		return
+	}
+
+	query := url.Values{"type": {typeStream}, "key": {key}, "entry_id": {id}}
+	http.Redirect(w, r, "/key-values/new?"+query.Encode(), http.StatusFound)
+}
+
+type StreamEntry struct {
+	ID     string            `json:"id"`
+	Fields map[string]string `json:"fields"`
+}
+
+// XREAD of the entries after ?from_id=, 0-0 reads from the beginning
+// ?block_ms= waits for new entries, up to 30s
+func readStream(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	query := r.URL.Query()
+
+	fromID := query.Get("from_id")
+	if len(fromID) < 1 {
+		fromID = "0-0"
+	}
+	if fromID == ">" {
+		writeJSONError(w, http.StatusBadRequest, "from_id > is only valid for consumer groups")
+		return
+	}
+	var count int64 = 10
+	if countStr := query.Get("count"); len(countStr) > 0 {
+		var err error
+		count, err = strconv.ParseInt(countStr, 10, 64)
+		if err != nil || count < 1 {
+			writeJSONError(w, http.StatusBadRequest, "query parameter count must be a positive integer")
+			return
+		}
+		count = min(count, maxStreamReadCount)
+	}
+	var block time.Duration
+	if blockStr := query.Get("block_ms"); len(blockStr) > 0 {
+		blockMs, err := strconv.ParseInt(blockStr, 10, 64)
+		if err != nil || blockMs < 0 {
+			writeJSONError(w, http.StatusBadRequest, "query parameter block_ms must be a non-negative integer")
+			return
+		}
+		// BLOCK 0 would wait forever
+		block = min(time.Duration(blockMs)*time.Millisecond, maxStreamReadBlock)
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), block+valkeyCmdTimeout)
+	defer cancel()
+
+	var cmd valkey.Completed
+	if block > 0 {
+		// XREAD with BLOCK takes a connection of the blocking pool
+		release, err := acquireBlockingConn(ctx, selectedCluster(r))
+		if err != nil {
+			log.Printf("Failed to read stream %v, err = %v\n", key, err)
+			writeJSONError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		defer release()
+		cmd = client.B().Xread().Count(count).Block(block.Milliseconds()).Streams().Key(key).Id(fromID).Build()
+	} else {
+		cmd = client.B().Xread().Count(count).Streams().Key(key).Id(fromID).Build()
+	}
+	streams, err := client.Do(ctx, cmd).AsXRead()
+	if valkey.IsValkeyNil(err) {
+		// no entries after from_id, or none within the wait
+		err = nil
+	}
+	if _, ok := valkey.IsValkeyErr(err); ok {
+		// e.g. an invalid from_id
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to read stream %v, err = %v\n", key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to read stream %v", key))
+		return
+	}
+
+	entries := make([]StreamEntry, 0, len(streams[key]))
+	for _, entry := range streams[key] {
+		entries = append(entries, StreamEntry{ID: entry.ID, Fields: entry.FieldValues})
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// XDEL, e.g. to remove personal data, answers the number of entries actually deleted
+func deleteStreamEntries(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	var body struct {
+		IDs []string `json:"ids"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if len(body.IDs) < 1 {
+		writeJSONError(w, http.StatusBadRequest, "ids must not be empty")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	deleted, err := replyInt64(client.Do(ctx, client.B().Xdel().Key(key).Id(body.IDs...).Build()))
+	if _, ok := valkey.IsValkeyErr(err); ok {
+		// e.g. an invalid entry ID
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete %v from stream %v, err = %v\n", body.IDs, key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to delete entries of stream %v", key))
+		return
+	}
+	log.Printf("Deleted %v of %v entries from stream %v\n", deleted, len(body.IDs), key)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deleted": deleted})
+}
+
+// XTRIM with MAXLEN and a length or MINID and an entry ID as threshold
+// approximate trimming with ~ only removes whole macro nodes and is much cheaper
+func trimStream(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	var body struct {
+		Strategy string `json:"strategy"`
+		// a length for MAXLEN, an entry ID for MINID
+		Threshold   interface{} `json:"threshold"`
+		Approximate bool        `json:"approximate"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	var threshold string
+	switch value := body.Threshold.(type) {
+	case float64:
+		if value < 0 || value != float64(int64(value)) {
+			writeJSONError(w, http.StatusBadRequest, "threshold must be a non-negative integer")
+			return
+		}
+		threshold = strconv.FormatInt(int64(value), 10)
+	case string:
+		threshold = value
+	}
+	if len(threshold) < 1 {
+		writeJSONError(w, http.StatusBadRequest, "threshold is required")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	var cmd valkey.Completed
+	switch strings.ToUpper(body.Strategy) {
+	case "MAXLEN":
+		if _, err := strconv.ParseInt(threshold, 10, 64); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "threshold of MAXLEN must be a non-negative integer")
+			return
+		}
+		maxlen := client.B().Xtrim().Key(key).Maxlen()
+		if body.Approximate {
+			cmd = maxlen.Almost().Threshold(threshold).Build()
+		} else {
+			cmd = maxlen.Threshold(threshold).Build()
+		}
+	case "MINID":
+		minid := client.B().Xtrim().Key(key).Minid()
+		if body.Approximate {
+			cmd = minid.Almost().Threshold(threshold).Build()
+		} else {
+			cmd = minid.Threshold(threshold).Build()
+		}
+	default:
+		writeJSONError(w, http.StatusBadRequest, "strategy must be MAXLEN or MINID")
+		return
+	}
+
+	trimmed, err := replyInt64(client.Do(ctx, cmd))
+	if _, ok := valkey.IsValkeyErr(err); ok {
+		// e.g. an invalid MINID
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to trim stream %v, err = %v\n", key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to trim stream %v", key))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"trimmed": trimmed})
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// set of the keys labeled with tag
+func tagKey(tag string) string {
+	return internalKey("tag", tag)
+}
+
+// keys labeled with tag, sorted
+func fetchTaggedKeys(ctx context.Context, client ValkeyClient, tag string) ([]string, error) {
+	keys, err := client.Do(ctx, client.B().Smembers().Key(tagKey(tag)).Build()).AsStrSlice()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// add tags to a key
+func addTags(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	var body struct {
+		Tags []string `json:"tags"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if len(body.Tags) < 1 {
+		writeJSONError(w, http.StatusBadRequest, "tags must not be empty")
+		return
+	}
+	for _, tag := range body.Tags {
+		if len(tag) < 1 {
+			writeJSONError(w, http.StatusBadRequest, "tags must not contain empty names")
+			return
+		}
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	cmds := make(valkey.Commands, 0, len(body.Tags))
+	for _, tag := range body.Tags {
+		cmds = append(cmds, client.B().Sadd().Key(tagKey(tag)).Member(key).Build())
+	}
+	for i, resp := range client.DoMulti(ctx, cmds...) {
+		if err := resp.Error(); err != nil {
+			log.Printf("Failed to tag key %v with %v, err = %v\n", key, body.Tags[i], valkeyErr(err))
+			writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to tag key %v with %v", key, body.Tags[i]))
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"key": key, "tags": body.Tags})
+}
+
+// remove a tag from a key
+func removeTag(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	tag := r.PathValue("tag")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	// 0 if the key was not tagged
+	removed, err := replyBool(client.Do(ctx, client.B().Srem().Key(tagKey(tag)).Member(key).Build()))
+	if err != nil {
+		log.Printf("Failed to remove tag %v of key %v, err = %v\n", tag, key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to remove tag %v of key %v", tag, key))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"removed": removed})
+}
+
+// keys labeled with a tag
+func getTaggedKeys(w http.ResponseWriter, r *http.Request) {
+	tag := r.PathValue("tag")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	keys, err := fetchTaggedKeys(ctx, client, tag)
+	if err != nil {
+		log.Printf("Failed to fetch keys of tag %v, err = %v\n", tag, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch keys of tag %v", tag))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tag": tag, "keys": keys})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// flat credentials of newer service versions, without the nested valkey object
+type flatCredentials struct {
+	Host          string  `json:"host"`
+	Port          int     `json:"port"`
+	Username      string  `json:"username"`
+	Password      string  `json:"password"`
+	CaCertificate *string `json:"ca_certificate"`
+}
+
+// credentials of a service instance, either nested
+//
+//	{"host": "...", "cacrt": "...", "valkey": {"password": "...", "port": 6379, "username": "..."}}
+//
+// or flat
+//
+//	{"host": "...", "port": 6379, "username": "...", "password": "...", "ca_certificate": "..."}
+func parseVcapCredentials(raw json.RawMessage) (ValkeyCredentials, error) {
+	var nested ValkeyCredentials
+	if err := json.Unmarshal(raw, &nested); err != nil {
+		return ValkeyCredentials{}, err
+	}
+	var flat flatCredentials
+	if err := json.Unmarshal(raw, &flat); err != nil {
+		return ValkeyCredentials{}, err
+	}
+
+	credentials := nested
+	if credentials.Valkey.Port == 0 {
+		credentials.Valkey = ValkeyDetails{
+			Password: flat.Password,
+			Port:     flat.Port,
+			Username: flat.Username,
+		}
+	}
+	if credentials.CaCertificate == nil {
+		credentials.CaCertificate = flat.CaCertificate
+	}
+	if len(credentials.Host) < 1 || credentials.Valkey.Port == 0 {
+		return ValkeyCredentials{}, fmt.Errorf("credentials contain no host and port")
+	}
+	return credentials, nil
+}
+
+// bound instances in a stable order, sorted by service label and in the order of each label
+func serviceInstances(vcapServices VcapServices) []ServiceInstance {
+	services := make([]string, 0, len(vcapServices))
+	for service := range vcapServices {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	instances := make([]ServiceInstance, 0)
+	for _, service := range services {
+		instances = append(instances, vcapServices[service]...)
+	}
+	return instances
+}
+
+// credentials of the first valid instance in serviceInstances order, stable across restarts
+func defaultServiceCredentials(vcapServices VcapServices) (ValkeyCredentials, error) {
+	for _, instance := range serviceInstances(vcapServices) {
+		credentials, err := parseVcapCredentials(instance.Credentials)
+		if err != nil {
+			log.Printf("Skipping service instance %v: %v", instance.Name, err)
+			continue
+		}
+		return credentials, nil
+	}
+	return ValkeyCredentials{}, fmt.Errorf("no valid services found in VCAP_SERVICES")
+}
+
+// instance by name or by its position in serviceInstances, the name takes precedence
+// the error lists the available instances
+func selectServiceInstance(vcapServices VcapServices, name string, indexStr string) (ServiceInstance, error) {
+	instances := serviceInstances(vcapServices)
+	available := make([]string, len(instances))
+	for i, instance := range instances {
+		available[i] = fmt.Sprintf("%v: %v", i, instance.Name)
+	}
+
+	if len(name) > 0 {
+		for _, instance := range instances {
+			if instance.Name == name {
+				return instance, nil
+			}
+		}
+		return ServiceInstance{}, fmt.Errorf("no service instance named %v in VCAP_SERVICES, available: %v", name, strings.Join(available, ", "))
+	}
+
+	index, err := strconv.Atoi(indexStr)
+	if err != nil || index < 0 || index >= len(instances) {
+		return ServiceInstance{}, fmt.Errorf("invalid VALKEY_SERVICE_INDEX %v, available: %v", indexStr, strings.Join(available, ", "))
+	}
+	return instances[index], nil
+}
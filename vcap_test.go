@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseVcapCredentials(t *testing.T) {
+	caCert := "-----BEGIN CERTIFICATE-----"
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    ValkeyCredentials
+		wantErr bool
+	}{
+		{
+			name: "nested",
+			raw:  `{"host": "valkey.service", "valkey": {"password": "pw", "port": 6379, "username": "user"}}`,
+			want: ValkeyCredentials{
+				Host:   "valkey.service",
+				Valkey: ValkeyDetails{Password: "pw", Port: 6379, Username: "user"},
+			},
+		},
+		{
+			name: "nested with cacrt",
+			raw:  `{"host": "valkey.service", "cacrt": "` + caCert + `", "valkey": {"password": "pw", "port": 6379, "username": "user"}}`,
+			want: ValkeyCredentials{
+				Host:          "valkey.service",
+				CaCertificate: &caCert,
+				Valkey:        ValkeyDetails{Password: "pw", Port: 6379, Username: "user"},
+			},
+		},
+		{
+			name: "flat",
+			raw:  `{"host": "valkey.service", "port": 6380, "username": "user", "password": "pw"}`,
+			want: ValkeyCredentials{
+				Host:   "valkey.service",
+				Valkey: ValkeyDetails{Password: "pw", Port: 6380, Username: "user"},
+			},
+		},
+		{
+			name: "flat with ca_certificate",
+			raw:  `{"host": "valkey.service", "port": 6380, "username": "user", "password": "pw", "ca_certificate": "` + caCert + `"}`,
+			want: ValkeyCredentials{
+				Host:          "valkey.service",
+				CaCertificate: &caCert,
+				Valkey:        ValkeyDetails{Password: "pw", Port: 6380, Username: "user"},
+			},
+		},
+		{
+			name: "nested object takes precedence",
+			raw:  `{"host": "valkey.service", "port": 6380, "password": "flat", "valkey": {"password": "nested", "port": 6379, "username": "user"}}`,
+			want: ValkeyCredentials{
+				Host:   "valkey.service",
+				Valkey: ValkeyDetails{Password: "nested", Port: 6379, Username: "user"},
+			},
+		},
+		{
+			name:    "malformed json",
+			raw:     `{"host": "valkey.service",`,
+			wantErr: true,
+		},
+		{
+			name:    "wrong port type",
+			raw:     `{"host": "valkey.service", "port": "6379"}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing host",
+			raw:     `{"port": 6379, "username": "user", "password": "pw"}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing port",
+			raw:     `{"host": "valkey.service", "username": "user", "password": "pw"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseVcapCredentials(json.RawMessage(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseVcapCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseVcapCredentials() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultServiceCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		services string
+		wantHost string
+		wantErr  bool
+	}{
+		{
+			name: "two bound services",
+			services: `{
+				"a9s-valkey80": [{"name": "second", "credentials": {"host": "second.service", "port": 6379}}],
+				"a9s-keyvalue": [{"name": "first", "credentials": {"host": "first.service", "port": 6379}}]
+			}`,
+			wantHost: "first.service",
+		},
+		{
+			name: "invalid first instance is skipped",
+			services: `{
+				"a9s-keyvalue": [{"name": "broken", "credentials": {"port": 6379}}, {"name": "valid", "credentials": {"host": "valid.service", "port": 6379}}],
+				"a9s-valkey80": [{"name": "other", "credentials": {"host": "other.service", "port": 6379}}]
+			}`,
+			wantHost: "valid.service",
+		},
+		{
+			name:     "no valid instance",
+			services: `{"a9s-keyvalue": [{"name": "broken", "credentials": {"port": 6379}}]}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var vcapServices VcapServices
+			if err := json.Unmarshal([]byte(tt.services), &vcapServices); err != nil {
+				t.Fatal(err)
+			}
+			// map order is random, the result must not be
+			for i := 0; i < 20; i++ {
+				got, err := defaultServiceCredentials(vcapServices)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("defaultServiceCredentials() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if got.Host != tt.wantHost {
+					t.Fatalf("defaultServiceCredentials() host = %v, want %v", got.Host, tt.wantHost)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadTrustProxy(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy string
+		vcapApp    string
+		want       bool
+	}{
+		{name: "default", want: false},
+		{name: "cloud foundry", vcapApp: `{"application_name": "app"}`, want: true},
+		{name: "disabled in cloud foundry", trustProxy: "false", vcapApp: `{"application_name": "app"}`, want: false},
+		{name: "enabled", trustProxy: "true", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUST_PROXY", tt.trustProxy)
+			t.Setenv("VCAP_APPLICATION", tt.vcapApp)
+			if got := loadTrustProxy(); got != tt.want {
+				t.Errorf("loadTrustProxy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// set at build time with -ldflags "-X main.version=<version>"
+var version = "dev"
+
+// set in main() right before serving, reveals silent restarts of the app
+var startTime time.Time
+
+func uptimeSeconds() int64 {
+	return int64(time.Since(startTime).Seconds())
+}
+
+// build information and the enabled feature flags
+func renderVersion(w http.ResponseWriter, r *http.Request) {
+	info := map[string]interface{}{
+		"version":        version,
+		"go_version":     runtime.Version(),
+		"features":       features,
+		"uptime_seconds": uptimeSeconds(),
+		"started_at":     startTime.UTC().Format(time.RFC3339),
+	}
+	if protocolVersion > 0 {
+		info["resp_version"] = protocolVersion
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" {
+				info["revision"] = setting.Value
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// maximum number of members returned by a range query
+const zsetRangeLimit = 100
+
+// range query of a sorted set, see parseZsetRange
+type ZsetRange struct {
+	// "score" or "lex"
+	By         string
+	Min        string
+	Max        string
+	Rev        bool
+	WithScores bool
+}
+
+type ZsetMember struct {
+	Member string `json:"member"`
+	// only set for score ranges with scores
+	Score *float64 `json:"score,omitempty"`
+}
+
+type ZsetViewModel struct {
+	Key string
+	ZsetRange
+	Members []ZsetMember
+	Error   string
+	// all scores are zero, the members are only ordered lexicographically
+	AllZeroScores bool
+}
+
+// range from ?by=, ?min=, ?max=, ?rev= and ?with_scores=, defaults to all members by score
+func parseZsetRange(query url.Values) ZsetRange {
+	zrange := ZsetRange{
+		By:         query.Get("by"),
+		Min:        query.Get("min"),
+		Max:        query.Get("max"),
+		Rev:        query.Get("rev") == "true",
+		WithScores: query.Get("with_scores") == "true",
+	}
+	if zrange.By == "lex" {
+		// lex ranges have no scores
+		zrange.WithScores = false
+		if len(zrange.Min) < 1 {
+			zrange.Min = "-"
+		}
+		if len(zrange.Max) < 1 {
+			zrange.Max = "+"
+		}
+		return zrange
+	}
+	zrange.By = "score"
+	if len(zrange.Min) < 1 {
+		zrange.Min = "-inf"
+	}
+	if len(zrange.Max) < 1 {
+		zrange.Max = "+inf"
+	}
+	return zrange
+}
+
+// lex bounds are - or + or a member prefixed with ( for exclusive or [ for inclusive
+func validLexBound(bound string) bool {
+	return bound == "-" || bound == "+" || strings.HasPrefix(bound, "(") || strings.HasPrefix(bound, "[")
+}
+
+// ZRANGEBYSCORE, ZRANGEBYLEX or their REV variants, limited to zsetRangeLimit members
+func fetchZsetRange(ctx context.Context, client ValkeyClient, key string, zrange ZsetRange) ([]ZsetMember, error) {
+	var cmd valkey.Completed
+	switch {
+	case zrange.By == "lex" && zrange.Rev:
+		cmd = client.B().Zrevrangebylex().Key(key).Max(zrange.Max).Min(zrange.Min).Limit(0, zsetRangeLimit).Build()
+	case zrange.By == "lex":
+		cmd = client.B().Zrangebylex().Key(key).Min(zrange.Min).Max(zrange.Max).Limit(0, zsetRangeLimit).Build()
+	case zrange.Rev && zrange.WithScores:
+		cmd = client.B().Zrevrangebyscore().Key(key).Max(zrange.Max).Min(zrange.Min).Withscores().Limit(0, zsetRangeLimit).Build()
+	case zrange.Rev:
+		cmd = client.B().Zrevrangebyscore().Key(key).Max(zrange.Max).Min(zrange.Min).Limit(0, zsetRangeLimit).Build()
+	case zrange.WithScores:
+		cmd = client.B().Zrangebyscore().Key(key).Min(zrange.Min).Max(zrange.Max).Withscores().Limit(0, zsetRangeLimit).Build()
+	default:
+		cmd = client.B().Zrangebyscore().Key(key).Min(zrange.Min).Max(zrange.Max).Limit(0, zsetRangeLimit).Build()
+	}
+
+	resp := client.Do(ctx, cmd)
+	if zrange.WithScores {
+		scores, err := resp.AsZScores()
+		if err != nil {
+			return nil, err
+		}
+		return toZsetMembers(scores), nil
+	}
+
+	names, err := resp.AsStrSlice()
+	if err != nil {
+		return nil, err
+	}
+	members := make([]ZsetMember, len(names))
+	for i, name := range names {
+		members[i] = ZsetMember{Member: name}
+	}
+	return members, nil
+}
+
+func getZsetRange(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	zrange := parseZsetRange(r.URL.Query())
+	if zrange.By == "lex" && (!validLexBound(zrange.Min) || !validLexBound(zrange.Max)) {
+		writeJSONError(w, http.StatusBadRequest, "lex bounds must be -, + or start with ( or [")
+		return
+	}
+	members, err := fetchZsetRange(ctx, client, key, zrange)
+	if _, ok := valkey.IsValkeyErr(err); ok {
+		// invalid ranges like min=a are rejected by the server
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid range: %v", err))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch range of key %v, err = %v\n", key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch range of key %v", key))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, members)
+}
+
+// members of a sorted set with range filters
+func renderZset(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	zrange := parseZsetRange(r.URL.Query())
+	// the page always shows scores where available
+	zrange.WithScores = zrange.By == "score"
+	viewModel := ZsetViewModel{Key: key, ZsetRange: zrange}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		renderError(w, r, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	if zrange.By == "lex" && (!validLexBound(zrange.Min) || !validLexBound(zrange.Max)) {
+		viewModel.Error = "lex bounds must be -, + or start with ( or ["
+		renderTemplate(w, "zset", "base", viewModel)
+		return
+	}
+	viewModel.Members, err = fetchZsetRange(ctx, client, key, zrange)
+	if err != nil {
+		log.Printf("Failed to fetch range of key %v, err = %v\n", key, valkeyErr(err))
+		viewModel.Error = fmt.Sprintf("failed to fetch the range: %v", valkeyErr(err))
+	}
+	viewModel.AllZeroScores = zrange.WithScores && len(viewModel.Members) > 0
+	for _, member := range viewModel.Members {
+		if member.Score != nil && *member.Score != 0 {
+			viewModel.AllZeroScores = false
+			break
+		}
+	}
+
+	renderTemplate(w, "zset", "base", viewModel)
+}
+
+// update the score of an existing member, ZADD XX never creates one
+func setZsetScore(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	member := r.PathValue("member")
+
+	var body struct {
+		Score *float64 `json:"score"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if body.Score == nil {
+		writeJSONError(w, http.StatusBadRequest, "score is required")
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	// CH counts changed scores, an unchanged score of an existing member answers 0 as well
+	err = client.Do(ctx, client.B().Zscore().Key(key).Member(member).Build()).Error()
+	if valkey.IsValkeyNil(err) {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("member %v of key %v not found", member, key))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch score of member %v of key %v, err = %v\n", member, key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch score of member %v", member))
+		return
+	}
+
+	changed, err := replyInt64(client.Do(ctx, client.B().Zadd().Key(key).Xx().Ch().ScoreMember().ScoreMember(*body.Score, member).Build()))
+	if err != nil {
+		log.Printf("Failed to set score of member %v of key %v, err = %v\n", member, key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to set score of member %v", member))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"member": member, "score": *body.Score, "changed": changed == 1})
+}
+
+// ?count= of the pop and peek endpoints, 1 by default
+func parsePopCount(r *http.Request) (int64, error) {
+	countStr := r.URL.Query().Get("count")
+	if len(countStr) < 1 {
+		return 1, nil
+	}
+	count, err := strconv.ParseInt(countStr, 10, 64)
+	if err != nil || count < 1 {
+		return 0, fmt.Errorf("query parameter count must be a positive integer")
+	}
+	return count, nil
+}
+
+func toZsetMembers(scores []valkey.ZScore) []ZsetMember {
+	members := make([]ZsetMember, len(scores))
+	for i, score := range scores {
+		members[i] = ZsetMember{Member: score.Member, Score: &score.Score}
+	}
+	return members
+}
+
+// ZPOPMIN or ZPOPMAX of ?count= members, for sorted sets used as priority queues
+func popZsetMembers(highest bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.PathValue("key")
+		count, err := parsePopCount(r)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		client, err := newClient(r)
+		if err != nil {
+			log.Printf("Failed to create connection: %v", err)
+			writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+			return
+		}
+		defer client.Close()
+
+		ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+		defer cancel()
+
+		cmd := client.B().Zpopmin().Key(key).Count(count).Build()
+		if highest {
+			cmd = client.B().Zpopmax().Key(key).Count(count).Build()
+		}
+		scores, err := client.Do(ctx, cmd).AsZScores()
+		if err != nil {
+			log.Printf("Failed to pop members of sorted set %v, err = %v\n", key, valkeyErr(err))
+			writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to pop members of sorted set %v", key))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, toZsetMembers(scores))
+	}
+}
+
+// the ?count= members ZPOPMIN would pop, without removing them
+func peekZsetMin(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	count, err := parsePopCount(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	client, err := newClient(r)
+	if err != nil {
+		log.Printf("Failed to create connection: %v", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "failed to connect to Valkey")
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := withDeadline(r.Context(), valkeyCmdTimeout)
+	defer cancel()
+
+	// ranks are ordered by score, so the first ranks are the lowest scores
+	scores, err := client.Do(ctx, client.B().Zrange().Key(key).Min("0").Max(strconv.FormatInt(count-1, 10)).Withscores().Build()).AsZScores()
+	if err != nil {
+		log.Printf("Failed to fetch members of sorted set %v, err = %v\n", key, valkeyErr(err))
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch members of sorted set %v", key))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toZsetMembers(scores))
+}